@@ -0,0 +1,92 @@
+package breaker
+
+import "time"
+
+// AdaptiveConcurrency replaces the fixed MaxConcurrent limit with one that is
+// adjusted from observed outcomes using additive increase, multiplicative decrease.
+// The limit grows by one while requests succeed and at least half of it is in use,
+// and is multiplied by BackoffRatio when a request fails or takes longer than
+// LatencyThreshold.
+type AdaptiveConcurrency struct {
+	InitialLimit     int
+	MinLimit         int
+	MaxLimit         int
+	LatencyThreshold time.Duration
+	BackoffRatio     float64
+}
+
+const defaultInitialLimit = 20
+const defaultMinLimit = 1
+const defaultMaxLimit = 200
+const defaultLimitBackoffRatio = 0.9
+
+type aimdLimiter struct {
+	limit     float64
+	min       float64
+	max       float64
+	threshold time.Duration
+	ratio     float64
+}
+
+func newAIMDLimiter(a *AdaptiveConcurrency) *aimdLimiter {
+	l := &aimdLimiter{
+		limit:     float64(a.InitialLimit),
+		min:       float64(a.MinLimit),
+		max:       float64(a.MaxLimit),
+		threshold: a.LatencyThreshold,
+		ratio:     a.BackoffRatio,
+	}
+	if l.min <= 0 {
+		l.min = defaultMinLimit
+	}
+	if l.max <= 0 {
+		l.max = defaultMaxLimit
+	}
+	if l.limit <= 0 {
+		l.limit = defaultInitialLimit
+	}
+	if l.ratio <= 0 || l.ratio >= 1 {
+		l.ratio = defaultLimitBackoffRatio
+	}
+	l.clamp()
+	return l
+}
+
+// onSample adjusts the limit after a request that completed with inFlight requests running.
+func (l *aimdLimiter) onSample(inFlight int, latency time.Duration, success bool) {
+	switch {
+	case !success || l.threshold > 0 && latency > l.threshold:
+		l.limit *= l.ratio
+	case float64(inFlight)*2 >= l.limit:
+		l.limit++
+	}
+	l.clamp()
+}
+
+func (l *aimdLimiter) clamp() {
+	if l.limit < l.min {
+		l.limit = l.min
+	}
+	if l.limit > l.max {
+		l.limit = l.max
+	}
+}
+
+func (l *aimdLimiter) current() int {
+	return int(l.limit)
+}
+
+// ConcurrencyLimit returns the limit of requests in flight in the closed state, 0 if unlimited.
+func (cb *CircuitBreaker) ConcurrencyLimit() int {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	return cb.concurrencyLimit()
+}
+
+func (cb *CircuitBreaker) concurrencyLimit() int {
+	if cb.limiter != nil {
+		return cb.limiter.current()
+	}
+	return cb.maxConcurrent
+}
@@ -0,0 +1,48 @@
+package breaker
+
+import (
+	"math"
+	"time"
+)
+
+// BackoffPolicy computes a delay from the number of consecutive attempts, starting at 1.
+// The circuit breaker uses it to grow the open state timeout when it trips repeatedly.
+type BackoffPolicy interface {
+	Backoff(attempt int) time.Duration
+}
+
+// ConstantBackoff always returns the same delay.
+type ConstantBackoff time.Duration
+
+func (b ConstantBackoff) Backoff(attempt int) time.Duration {
+	return time.Duration(b)
+}
+
+// ExponentialBackoff multiplies Initial by Multiplier for every attempt after the first,
+// up to Max. Multiplier defaults to 2 and a zero Max means no cap.
+type ExponentialBackoff struct {
+	Initial    time.Duration
+	Max        time.Duration
+	Multiplier float64
+}
+
+const defaultBackoffMultiplier = 2
+
+func (b ExponentialBackoff) Backoff(attempt int) time.Duration {
+	multiplier := b.Multiplier
+	if multiplier <= 1 {
+		multiplier = defaultBackoffMultiplier
+	}
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	d := float64(b.Initial) * math.Pow(multiplier, float64(attempt-1))
+	if b.Max > 0 && d > float64(b.Max) {
+		return b.Max
+	}
+	if d > math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(d)
+}
@@ -1,6 +1,7 @@
 package breaker
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sync"
@@ -20,6 +21,12 @@ var (
 	ErrTooManyRequests = errors.New("too many requests")
 	// ErrOpenState is returned when the CB state is open
 	ErrOpenState = errors.New("circuit breaker is open")
+	// ErrBulkheadFull is returned when the CB state is closed and the requests in flight reach the cb maxConcurrent
+	ErrBulkheadFull = errors.New("too many concurrent requests")
+	// ErrQueueTimeout is returned when a request waited cb maxWait in the queue without getting a slot
+	ErrQueueTimeout = errors.New("timed out waiting for a slot")
+	// ErrRetryBudgetExhausted is returned, wrapping the last error, when a retry would exceed the cb retry budget
+	ErrRetryBudgetExhausted = errors.New("retry budget exhausted")
 )
 
 // String implements stringer interface.
@@ -36,12 +43,56 @@ func (s State) String() string {
 	}
 }
 
+// MarshalText implements encoding.TextMarshaler, so that states encode by name.
+func (s State) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *State) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "closed":
+		*s = StateClosed
+	case "half-open":
+		*s = StateHalfOpen
+	case "open":
+		*s = StateOpen
+	default:
+		return fmt.Errorf("unknown state: %q", text)
+	}
+	return nil
+}
+
 type Counts struct {
 	Requests           int
 	TotalSuccess       int
 	TotalFail          int
+	TotalSlow          int
 	ConsecutiveSuccess int
 	ConsecutiveFail    int
+	// Buckets holds the per bucket totals when a rolling time window is configured.
+	Buckets []Bucket
+	// Errors holds the failures of the generation per error class when
+	// Settings.ErrorClassifier is set.
+	Errors map[string]int
+}
+
+// FailureRate returns the ratio of failures to completed requests.
+func (c Counts) FailureRate() float64 {
+	total := c.TotalSuccess + c.TotalFail
+	if total == 0 {
+		return 0
+	}
+	return float64(c.TotalFail) / float64(total)
+}
+
+// SlowCallRate returns the ratio of slow calls to completed requests.
+func (c Counts) SlowCallRate() float64 {
+	total := c.TotalSuccess + c.TotalFail
+	if total == 0 {
+		return 0
+	}
+	return float64(c.TotalSlow) / float64(total)
 }
 
 func (c *Counts) onRequest() {
@@ -60,187 +111,818 @@ func (c *Counts) onFail() {
 	c.ConsecutiveSuccess = 0
 }
 
+func (c *Counts) onSlow() {
+	c.TotalSlow++
+}
+
+func (c *Counts) onError(class string) {
+	c.onErrors(class, 1)
+}
+
+func (c *Counts) onErrors(class string, n int) {
+	if c.Errors == nil {
+		c.Errors = make(map[string]int)
+	}
+	c.Errors[class] += n
+}
+
 func (c *Counts) clear() {
 	c.Requests = 0
 	c.TotalSuccess = 0
 	c.TotalFail = 0
+	c.TotalSlow = 0
 	c.ConsecutiveSuccess = 0
 	c.ConsecutiveFail = 0
+	c.Buckets = nil
+	c.Errors = nil
 }
 
 type Settings struct {
-	Timeout     time.Duration
-	MaxRequests int
-	ReadyToTrip func(c Counts) bool
+	Name        string
+	OpenTimeout time.Duration
+	// Deprecated: Timeout is used as OpenTimeout when the latter is not set.
+	Timeout               time.Duration
+	Interval              time.Duration
+	BackoffPolicy         BackoffPolicy
+	MaxRequests           int
+	SingleProbe           bool
+	MaxConcurrentProbes   int
+	MaxConcurrent         int
+	AdaptiveConcurrency   *AdaptiveConcurrency
+	MaxQueue              int
+	MaxWait               time.Duration
+	SuccessThreshold      int
+	WindowSize            int
+	RollingWindow         time.Duration
+	RollingBuckets        int
+	FailureRateThreshold  float64
+	MinimumRequests       int
+	SlowCallThreshold     time.Duration
+	SlowCallRateThreshold float64
+	RetryPolicy           *RetryPolicy
+	Classifier            func(err error) Outcome
+	IgnoredErrors         []error
+	FatalErrors           []error
+	TripImmediately       func(err error) bool
+	PanicPolicy           PanicPolicy
+	OnPanic               func(v interface{})
+	ReadyToTrip           func(c Counts) bool
+	Disabled              bool
+	CacheTTL              time.Duration
+	CacheSize             int
+	PublishExpvar         bool
+	Logger                Logger
+	EventBuffer           int
+	ErrorClassifier       func(err error) string
+	Store                 Store
+	SharedState           SharedState
+	SyncInterval          time.Duration
+	Clock                 Clock
+	// OnStateChange is called whenever the state changes. It runs with the
+	// breaker locked and must not call back into it.
+	OnStateChange func(name string, from State, to State)
 }
 
 type CircuitBreaker struct {
-	timeout     time.Duration
-	maxRequests int
-	readyToTrip func(c Counts) bool
+	name             string
+	openTimeout      time.Duration
+	interval         time.Duration
+	backoff          BackoffPolicy
+	maxRequests      int
+	singleProbe      bool
+	maxProbes        int
+	maxConcurrent    int
+	limiter          *aimdLimiter
+	maxQueue         int
+	maxWait          time.Duration
+	successThreshold int
+	readyToTrip      func(c Counts) bool
+	window           window
+	cache            *resultCache
+	retryPolicy      *RetryPolicy
+	retryBudget      *retryBudget
+	classifier       func(err error) Outcome
+	panicPolicy      PanicPolicy
+	onPanic          func(v interface{})
+	onStateChange    func(name string, from State, to State)
+	logger           Logger
+	eventBuffer      int
+	errorClassifier  func(err error) string
+	store            Store
+	shared           SharedState
+	syncInterval     time.Duration
+	settings         Settings
+	clock            Clock
+
+	minimumRequests       int
+	slowCallThreshold     time.Duration
+	slowCallRateThreshold float64
 
 	mutex      sync.Mutex
 	state      State
 	generation int
 	counts     Counts
 	expiry     time.Time
+	trips      int
+	probes     int
+	override   override
+	disabled   bool
+	inFlight   int
+	queued     int
+	waiters    []chan struct{}
+	metrics    Metrics
+	events     []chan Event
+
+	lastError      error
+	tripError      error
+	lastTransition time.Time
+	lastRequest    time.Time
+
+	sharedGeneration int
+	pending          Counts
+	lastSync         time.Time
+	syncing          bool
+	adopting         bool
+	publishing       sync.Mutex
+
+	unsaved *Snapshot
+	saving  bool
+
+	lastRejectionLog     time.Time
+	suppressedRejections int
 }
 
-const defaultTimeOut = 60 * time.Second
+const defaultOpenTimeout = 60 * time.Second
+const defaultInterval = 60 * time.Second
 const defaultMaxRequests = 5
+const defaultRollingBuckets = 10
 
 func defaultReadyToTrip(c Counts) bool {
 	return c.ConsecutiveFail >= 5
 }
 
+func failureRateReadyToTrip(threshold float64, minimum int) func(c Counts) bool {
+	return func(c Counts) bool {
+		if c.TotalSuccess+c.TotalFail < minimum {
+			return false
+		}
+		return c.FailureRate() >= threshold
+	}
+}
+
 func NewCircuitBreaker(setings Settings) *CircuitBreaker {
 	cb := new(CircuitBreaker)
+	cb.name = setings.Name
+
+	if setings.Clock == nil {
+		cb.clock = SystemClock{}
+	} else {
+		cb.clock = setings.Clock
+	}
+	cb.configure(setings)
+
+	now := cb.clock.Now()
+	cb.metrics.Transitions = make(map[State]uint64)
+	cb.lastRequest = now
+	cb.state = StateClosed
+	cb.newGeneration(now)
+
+	if cb.store != nil {
+		cb.load()
+	}
+	if setings.PublishExpvar {
+		cb.PublishExpvar()
+	}
+
+	return cb
+}
 
-	if setings.Timeout <= 0 {
-		cb.timeout = defaultTimeOut
+// configure applies setings to the circuit breaker. The window, limiter,
+// cache and retry budget are only created anew when their settings change.
+func (cb *CircuitBreaker) configure(setings Settings) {
+	switch {
+	case setings.OpenTimeout > 0:
+		cb.openTimeout = setings.OpenTimeout
+	case setings.Timeout > 0:
+		cb.openTimeout = setings.Timeout
+	default:
+		cb.openTimeout = defaultOpenTimeout
+	}
+
+	if setings.BackoffPolicy == nil {
+		cb.backoff = ConstantBackoff(cb.openTimeout)
 	} else {
-		cb.timeout = setings.Timeout
+		cb.backoff = setings.BackoffPolicy
 	}
 
-	if setings.Timeout <= 0 {
+	if setings.Interval <= 0 {
+		cb.interval = defaultInterval
+	} else {
+		cb.interval = setings.Interval
+	}
+
+	if setings.MaxRequests <= 0 {
 		cb.maxRequests = defaultMaxRequests
 	} else {
 		cb.maxRequests = setings.MaxRequests
 	}
 
-	if setings.ReadyToTrip == nil {
+	switch {
+	case setings.ReadyToTrip != nil:
+		cb.readyToTrip = setings.ReadyToTrip
+	case setings.FailureRateThreshold > 0:
+		cb.readyToTrip = failureRateReadyToTrip(setings.FailureRateThreshold, setings.MinimumRequests)
+	default:
 		cb.readyToTrip = defaultReadyToTrip
+	}
+
+	cb.singleProbe = setings.SingleProbe
+	cb.maxProbes = setings.MaxConcurrentProbes
+	cb.maxConcurrent = setings.MaxConcurrent
+	if setings.AdaptiveConcurrency == nil {
+		cb.limiter = nil
+	} else if cb.limiter == nil || setings.AdaptiveConcurrency != cb.settings.AdaptiveConcurrency {
+		cb.limiter = newAIMDLimiter(setings.AdaptiveConcurrency)
+	}
+	cb.maxQueue = setings.MaxQueue
+
+	if setings.MaxWait <= 0 {
+		cb.maxWait = defaultMaxWait
 	} else {
-		cb.readyToTrip = setings.ReadyToTrip
+		cb.maxWait = setings.MaxWait
 	}
 
-	cb.refresh(time.Now())
+	// Without MaxConcurrentProbes a generation admits at most maxRequests
+	// probes, so a higher threshold could never be reached.
+	if setings.SuccessThreshold <= 0 || (setings.MaxConcurrentProbes <= 0 && setings.SuccessThreshold > cb.maxRequests) {
+		cb.successThreshold = cb.maxRequests
+	} else {
+		cb.successThreshold = setings.SuccessThreshold
+	}
 
-	cb.state = StateClosed
+	cb.minimumRequests = setings.MinimumRequests
+	cb.slowCallThreshold = setings.SlowCallThreshold
+	cb.slowCallRateThreshold = setings.SlowCallRateThreshold
+
+	if setings.WindowSize != cb.settings.WindowSize ||
+		setings.RollingWindow != cb.settings.RollingWindow || setings.RollingBuckets != cb.settings.RollingBuckets {
+		cb.window = nil
+		if setings.WindowSize > 0 {
+			cb.window = newCountWindow(setings.WindowSize)
+		} else if setings.RollingWindow > 0 {
+			buckets := setings.RollingBuckets
+			if buckets <= 0 {
+				buckets = defaultRollingBuckets
+			}
+			cb.window = newTimeWindow(setings.RollingWindow, buckets)
+		}
+	}
 
-	cb.generation = 0
+	cb.disabled = setings.Disabled
+	if cb.cache == nil || setings.CacheTTL != cb.settings.CacheTTL || setings.CacheSize != cb.settings.CacheSize {
+		cb.cache = newResultCache(setings.CacheTTL, setings.CacheSize)
+	}
+	if setings.Classifier == nil {
+		cb.classifier = defaultClassifier
+	} else {
+		cb.classifier = setings.Classifier
+	}
+	if len(setings.IgnoredErrors) > 0 {
+		cb.classifier = ignoring(cb.classifier, setings.IgnoredErrors)
+	}
+	if len(setings.FatalErrors) > 0 || setings.TripImmediately != nil {
+		cb.classifier = tripping(cb.classifier, setings.FatalErrors, setings.TripImmediately)
+	}
 
-	return cb
+	cb.panicPolicy = setings.PanicPolicy
+	cb.onPanic = setings.OnPanic
+	cb.onStateChange = setings.OnStateChange
+	cb.logger = setings.Logger
+	cb.errorClassifier = setings.ErrorClassifier
+	cb.store = setings.Store
+	cb.shared = setings.SharedState
+	if setings.SyncInterval <= 0 {
+		cb.syncInterval = defaultSyncInterval
+	} else {
+		cb.syncInterval = setings.SyncInterval
+	}
+	if setings.EventBuffer <= 0 {
+		cb.eventBuffer = defaultEventBuffer
+	} else {
+		cb.eventBuffer = setings.EventBuffer
+	}
+	if setings.RetryPolicy != cb.retryPolicy {
+		cb.retryBudget = nil
+		if setings.RetryPolicy != nil && setings.RetryPolicy.Budget != nil {
+			cb.retryBudget = newRetryBudget(setings.RetryPolicy.Budget)
+		}
+	}
+	cb.retryPolicy = setings.RetryPolicy
+
+	cb.settings = setings
 }
 
-func (cb *CircuitBreaker) refresh(t time.Time) {
-	cb.generation++
-	cb.counts.clear()
-	var zero = time.Time{}
-	switch cb.state {
-	case StateClosed:
-		cb.expiry = t.Add(cb.timeout)
-	default:
-		cb.expiry = zero
+// UpdateSettings retunes the circuit breaker at runtime, keeping its state,
+// counts and metrics. The name, Store, Clock and PublishExpvar can't be
+// changed. A changed window starts empty, and the current open or closed
+// interval keeps the expiry it started with.
+func (cb *CircuitBreaker) UpdateSettings(setings Settings) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	setings.Name = cb.name
+	setings.Store = cb.store
+	setings.Clock = cb.settings.Clock
+	setings.PublishExpvar = cb.settings.PublishExpvar
+
+	cb.configure(setings)
+	cb.logSettings()
+	cb.emit(Event{Type: EventSettingsChange, Time: cb.clock.Now()})
+}
+
+// Name returns the name of the circuit breaker.
+func (cb *CircuitBreaker) Name() string {
+	return cb.name
+}
+
+// State returns the current state of the circuit breaker.
+func (cb *CircuitBreaker) State() State {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	state, _ := cb.currentState(cb.clock.Now())
+	return state
+}
+
+// RetryAfter returns how long the circuit breaker stays open, 0 if it is not open.
+func (cb *CircuitBreaker) RetryAfter() time.Duration {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	now := cb.clock.Now()
+	if state, _ := cb.currentState(now); state != StateOpen || cb.override == overrideOpen {
+		return 0
 	}
+	return cb.expiry.Sub(now)
+}
+
+// Counts returns a snapshot of the counts of the current generation.
+func (cb *CircuitBreaker) Counts() Counts {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	now := cb.clock.Now()
+	cb.currentState(now)
+	return cb.snapshot(now)
+}
+
+// LastError returns the error of the most recent failure, nil if none.
+// Failures reported through Allow carry no error.
+func (cb *CircuitBreaker) LastError() error {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	return cb.lastError
+}
+
+// TripError returns the error of the request that last opened the circuit,
+// nil if it never opened or was opened by a slow success.
+func (cb *CircuitBreaker) TripError() error {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	return cb.tripError
+}
+
+// LastTransition returns the time of the last state change, the zero time if none.
+func (cb *CircuitBreaker) LastTransition() time.Time {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	return cb.lastTransition
 }
 
+// Execute runs req if the circuit breaker accepts the request and records its outcome.
+// By default a non-nil error returned by req counts as a failure, see Settings.Classifier.
 func (cb *CircuitBreaker) Execute(req func() (interface{}, error)) (interface{}, error) {
-	generation, err := cb.beforeRequest()
+	return Execute(cb, req)
+}
 
-	if err != nil {
+// ExecuteContext is like Execute but passes ctx through to req.
+// If ctx is already done the request is rejected with ctx.Err() without being counted.
+func (cb *CircuitBreaker) ExecuteContext(ctx context.Context, req func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
 
+	return execute(ctx, cb, func() (interface{}, error) {
+		return req(ctx)
+	})
+}
+
+// Do runs req through the circuit breaker for calls that produce no value.
+func (cb *CircuitBreaker) Do(req func() error) error {
+	_, err := Execute(cb, func() (struct{}, error) {
+		return struct{}{}, req()
+	})
+	return err
+}
+
+// Execute is the type safe variant of CircuitBreaker.Execute. It avoids
+// the interface{} round trip and the type assertion on the result.
+func Execute[T any](cb *CircuitBreaker, req func() (T, error)) (T, error) {
+	return execute(context.Background(), cb, req)
+}
+
+// execute runs req like Execute. Retries stop once ctx is done.
+func execute[T any](ctx context.Context, cb *CircuitBreaker, req func() (T, error)) (res T, err error) {
+	var zero T
+
+	a, err := cb.beforeRequest(ctx)
+	if err != nil {
+		return zero, err
+	}
+
+	start := cb.clock.Now()
 	defer func() {
 		e := recover()
-		if e != nil {
-			cb.afterRequest(generation, false)
+		if e == nil {
+			return
+		}
+
+		if a.onPanic != nil {
+			a.onPanic(e)
+		}
+		switch a.panicPolicy {
+		case PanicIgnore:
+			cb.afterRequest(a.generation, OutcomeIgnored, nil, start)
+			panic(e)
+		case PanicConvertToError:
+			res, err = zero, &PanicError{Value: e}
+			cb.afterRequest(a.generation, OutcomeFailure, err, start)
+		default:
+			cb.afterRequest(a.generation, OutcomeFailure, &PanicError{Value: e}, start)
 			panic(e)
 		}
 	}()
 
-	res, err := req()
-	cb.afterRequest(generation, err != nil)
+	res, err = call(ctx, cb, a, req)
+	cb.afterRequest(a.generation, a.classifier(err), err, start)
 
 	return res, err
 }
 
-func (cb *CircuitBreaker) beforeRequest() (int, error) {
+// Allow checks if a request can proceed without running it through a closure.
+// On success the caller must invoke done exactly once with the outcome of the request.
+func (cb *CircuitBreaker) Allow() (done func(success bool), err error) {
+	a, err := cb.beforeRequest(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	start := cb.clock.Now()
+	return func(success bool) {
+		cb.afterRequest(a.generation, outcomeOf(success), nil, start)
+	}, nil
+}
+
+// AllowOutcome is like Allow but done takes the outcome of the request, so that
+// a request can also be ignored, e.g. when it was canceled before completing.
+func (cb *CircuitBreaker) AllowOutcome() (done func(outcome Outcome), err error) {
+	a, err := cb.beforeRequest(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	start := cb.clock.Now()
+	return func(outcome Outcome) {
+		cb.afterRequest(a.generation, outcome, nil, start)
+	}, nil
+}
+
+// admission is a request accepted by beforeRequest. It carries the settings
+// the request runs with, copied under the lock so that UpdateSettings can't
+// change them while the request is in flight.
+type admission struct {
+	generation  int
+	classifier  func(err error) Outcome
+	panicPolicy PanicPolicy
+	onPanic     func(v interface{})
+	retryPolicy *RetryPolicy
+	retryBudget *retryBudget
+}
+
+// beforeRequest admits a request or returns the error rejecting it. A request
+// queued for a slot gives up when ctx is done.
+func (cb *CircuitBreaker) beforeRequest(ctx context.Context) (a admission, err error) {
 	cb.mutex.Lock()
 	defer cb.mutex.Unlock()
+	defer func() {
+		if err != nil {
+			now := cb.clock.Now()
+			cb.metrics.Rejections++
+			cb.logRejection(err, now)
+			cb.emit(Event{Type: EventRejection, Time: now, Err: err})
+		}
+	}()
 
-	cb.counts.onRequest()
-	currState, generation := cb.currentState(time.Now())
-	if currState == StateOpen {
-		return generation, ErrOpenState
+	cb.lastRequest = cb.clock.Now()
+	if cb.shared != nil {
+		cb.maybeSync(cb.lastRequest)
 	}
-	if currState == StateHalfOpen && cb.counts.Requests > cb.maxRequests {
-		return generation, ErrTooManyRequests
+
+	var timer Timer
+	for {
+		currState, generation := cb.currentState(cb.clock.Now())
+
+		err = nil
+		if !cb.disabled {
+			err = cb.admit(currState)
+			if err == nil && currState == StateClosed && timer == nil && len(cb.waiters) > 0 {
+				// Queued requests go first, the free slot is theirs.
+				cb.wakeNext()
+				err = ErrBulkheadFull
+			}
+		}
+
+		if err == ErrBulkheadFull && cb.maxQueue > 0 {
+			requeued := timer != nil
+			if timer == nil {
+				if cb.queued >= cb.maxQueue {
+					return a, err
+				}
+				cb.queued++
+				defer func() { cb.queued-- }()
+
+				timer = cb.clock.NewTimer(cb.maxWait)
+				defer timer.Stop()
+			}
+			if err = cb.wait(ctx, timer, requeued); err != nil {
+				return a, err
+			}
+			continue
+		}
+		if err != nil {
+			if timer != nil {
+				// The slot this request was woken for is left to the next one.
+				cb.wakeNext()
+			}
+			return a, err
+		}
+
+		if currState == StateHalfOpen {
+			cb.probes++
+		}
+		cb.inFlight++
+		cb.counts.onRequest()
+		cb.metrics.Requests++
+		return admission{
+			generation:  generation,
+			classifier:  cb.classifier,
+			panicPolicy: cb.panicPolicy,
+			onPanic:     cb.onPanic,
+			retryPolicy: cb.retryPolicy,
+			retryBudget: cb.retryBudget,
+		}, nil
 	}
+}
 
-	return generation, nil
+// admit returns the error rejecting a request in currState, if any.
+func (cb *CircuitBreaker) admit(currState State) error {
+	switch currState {
+	case StateClosed:
+		if limit := cb.concurrencyLimit(); limit > 0 && cb.inFlight >= limit {
+			return ErrBulkheadFull
+		}
+	case StateOpen:
+		return ErrOpenState
+	case StateHalfOpen:
+		if cb.singleProbe && cb.probes > 0 {
+			return ErrOpenState
+		}
+		if cb.maxProbes > 0 {
+			if cb.probes >= cb.maxProbes {
+				return ErrTooManyRequests
+			}
+		} else if cb.counts.Requests >= cb.maxRequests {
+			return ErrTooManyRequests
+		}
+	}
+	return nil
 }
 
-func (cb *CircuitBreaker) afterRequest(before int, isSuccess bool) {
+func (cb *CircuitBreaker) afterRequest(before int, outcome Outcome, err error, start time.Time) {
 	cb.mutex.Lock()
 	defer cb.mutex.Unlock()
 
-	now := time.Now()
-	currState, generation := cb.currentState(time.Now())
+	now := cb.clock.Now()
+	if cb.limiter != nil {
+		cb.limiter.onSample(cb.inFlight, now.Sub(start), outcome == OutcomeSuccess || outcome == OutcomeIgnored)
+	}
+	cb.release()
+	cb.metrics.onOutcome(outcome)
+	if err != nil && (outcome == OutcomeFailure || outcome == OutcomeFatal) {
+		cb.lastError = err
+	}
+
+	currState, generation := cb.currentState(now)
 
 	if generation != before {
 		return
 	}
+	if currState == StateHalfOpen {
+		cb.probes--
+		if outcome == OutcomeIgnored {
+			// An ignored probe proves nothing, so it frees its slot for another one.
+			cb.counts.Requests--
+		} else {
+			cb.emit(Event{Type: EventProbe, Time: now, Success: outcome == OutcomeSuccess})
+		}
+	}
 
-	if isSuccess {
-		cb.onSuccess(currState, now)
-	} else {
-		cb.onFail(currState, now)
+	if err != nil && cb.errorClassifier != nil && (outcome == OutcomeFailure || outcome == OutcomeFatal) {
+		cb.counts.onError(cb.errorClassifier(err))
+	}
+
+	prev := cb.state
+	slow := cb.slowCallThreshold > 0 && now.Sub(start) >= cb.slowCallThreshold
+	if cb.shared != nil {
+		cb.pending.onRequest()
+		switch outcome {
+		case OutcomeSuccess:
+			cb.pending.onSuccess()
+		case OutcomeFailure, OutcomeFatal:
+			cb.pending.onFail()
+		}
+		if slow {
+			cb.pending.onSlow()
+		}
+	}
+
+	switch outcome {
+	case OutcomeSuccess:
+		cb.onSuccess(currState, slow, now)
+	case OutcomeFailure:
+		cb.onFail(currState, slow, now)
+	case OutcomeFatal:
+		cb.onFail(currState, slow, now)
+		cb.setState(StateOpen, now)
+	}
+	if cb.state == StateOpen && prev != StateOpen {
+		cb.tripError = err
 	}
 }
 
-func (cb *CircuitBreaker) onSuccess(currState State, t time.Time) {
+func (cb *CircuitBreaker) onSuccess(currState State, slow bool, t time.Time) {
+	cb.counts.onSuccess()
+	if slow {
+		cb.counts.onSlow()
+	}
+	if cb.window != nil {
+		cb.window.record(true, slow, t)
+	}
+
 	switch currState {
 	case StateClosed:
-		cb.counts.onSuccess()
-		if cb.readyToTrip(cb.counts) {
+		if slow && cb.shouldTrip(cb.snapshot(t)) {
 			cb.setState(StateOpen, t)
 		}
 	case StateHalfOpen:
-		cb.counts.onSuccess()
-		if cb.counts.ConsecutiveSuccess >= cb.maxRequests {
+		if cb.counts.ConsecutiveSuccess >= cb.successThreshold {
 			cb.setState(StateClosed, t)
 		}
 	}
 }
 
-func (cb *CircuitBreaker) onFail(currState State, t time.Time) {
+func (cb *CircuitBreaker) onFail(currState State, slow bool, t time.Time) {
+	cb.counts.onFail()
+	if slow {
+		cb.counts.onSlow()
+	}
+	if cb.window != nil {
+		cb.window.record(false, slow, t)
+	}
+
 	switch currState {
 	case StateClosed:
-		cb.counts.onFail()
+		if cb.shouldTrip(cb.snapshot(t)) {
+			cb.setState(StateOpen, t)
+		}
 	case StateHalfOpen:
-		cb.counts.onFail()
 		cb.setState(StateOpen, t)
+	}
+}
 
+// shouldTrip reports whether the closed breaker must open, either because
+// readyToTrip says so or because too many calls were slow.
+func (cb *CircuitBreaker) shouldTrip(c Counts) bool {
+	if cb.readyToTrip(c) {
+		return true
+	}
+	if cb.slowCallRateThreshold <= 0 || c.TotalSuccess+c.TotalFail < cb.minimumRequests {
+		return false
 	}
+	return c.SlowCallRate() >= cb.slowCallRateThreshold
+}
+
+// snapshot returns the counts as seen by readyToTrip at t.
+// With a window the totals cover only the recent requests kept by the window.
+func (cb *CircuitBreaker) snapshot(t time.Time) Counts {
+	c := cb.counts
+	if c.Errors != nil {
+		c.Errors = make(map[string]int, len(cb.counts.Errors))
+		for class, n := range cb.counts.Errors {
+			c.Errors[class] = n
+		}
+	}
+	if cb.window != nil {
+		cb.window.apply(&c, t)
+	}
+	return c
 }
 
 func (cb *CircuitBreaker) currentState(t time.Time) (State, int) {
-	if cb.state == StateClosed && cb.expiry.Before(t) {
-		cb.setState(StateHalfOpen, time.Now())
+	switch cb.override {
+	case overrideOpen:
+		return StateOpen, cb.generation
+	case overrideClosed:
+		return StateClosed, cb.generation
 	}
-	return cb.state, int(cb.generation)
+
+	switch cb.state {
+	case StateClosed:
+		if !t.Before(cb.expiry) {
+			cb.newGeneration(t)
+		}
+	case StateOpen:
+		if !t.Before(cb.expiry) {
+			cb.setState(StateHalfOpen, t)
+		}
+	}
+	return cb.state, cb.generation
 }
 
 func (cb *CircuitBreaker) setState(s State, t time.Time) {
-	if s == cb.state {
+	if s == cb.state || cb.override != overrideNone {
 		return
 	}
 
+	prev := cb.state
+	cb.lastTransition = t
+	counts := cb.snapshot(t)
+	cb.logTransition(prev, s, counts)
+	cb.emit(Event{Type: EventTransition, Time: t, From: prev, To: s, Counts: counts})
+	if s == StateOpen {
+		cb.emit(Event{Type: EventTrip, Time: t, From: prev, To: s, Counts: counts})
+	}
 	cb.state = s
+	cb.metrics.Transitions[s]++
+	switch s {
+	case StateOpen:
+		cb.trips++
+	case StateClosed:
+		cb.trips = 0
+	}
+	if cb.window != nil {
+		cb.window.clear()
+	}
 	cb.newGeneration(t)
+
+	if cb.store != nil {
+		cb.save(t)
+	}
+	if cb.shared != nil {
+		cb.pending = Counts{}
+		if !cb.adopting {
+			cb.publish()
+		}
+	}
+	if cb.onStateChange != nil {
+		cb.onStateChange(cb.name, prev, s)
+	}
+}
+
+// idle reports whether the circuit breaker has no request in flight and received none for ttl.
+func (cb *CircuitBreaker) idle(t time.Time, ttl time.Duration) bool {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	return cb.inFlight == 0 && t.Sub(cb.lastRequest) >= ttl
 }
 
 func (cb *CircuitBreaker) newGeneration(t time.Time) {
 	cb.counts.clear()
 	cb.generation++
+	cb.probes = 0
 
 	var zero time.Time
 
-	if cb.state == StateOpen {
-		cb.expiry = t.Add(cb.timeout)
-	} else {
+	switch cb.state {
+	case StateClosed:
+		cb.expiry = t.Add(cb.interval)
+	case StateOpen:
+		cb.expiry = t.Add(cb.backoff.Backoff(cb.trips))
+	default:
 		cb.expiry = zero
 	}
 }
@@ -0,0 +1,10 @@
+module github.com/sj902/breaker/breakeramqp
+
+go 1.21
+
+require (
+	github.com/rabbitmq/amqp091-go v1.10.0
+	github.com/sj902/breaker v0.0.0
+)
+
+replace github.com/sj902/breaker => ../
@@ -0,0 +1,109 @@
+// Package breakeramqp guards AMQP 0-9-1 publishing with a circuit breaker.
+package breakeramqp
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/sj902/breaker"
+)
+
+// ErrNacked is returned by Publish when the broker negatively acknowledges a message.
+var ErrNacked = errors.New("message nacked by the broker")
+
+// Publisher publishes messages through Breaker on a channel it opens with Dial.
+// Opening the channel is part of the guarded call, so failing reconnects count
+// as failures and stop being attempted while the breaker is open.
+type Publisher struct {
+	Breaker *breaker.CircuitBreaker
+	// Dial opens a new channel, e.g. from a shared connection.
+	Dial func() (*amqp.Channel, error)
+	// Confirms puts the channel in confirm mode and waits for the broker to
+	// acknowledge every message; a nack counts as a failure.
+	Confirms bool
+
+	mutex   sync.Mutex
+	channel *amqp.Channel
+}
+
+// Publish publishes msg to exchange with the routing key.
+func (p *Publisher) Publish(ctx context.Context, exchange, key string, msg amqp.Publishing) error {
+	return p.Breaker.Do(func() error {
+		ch, err := p.open()
+		if err != nil {
+			return err
+		}
+
+		err = p.publish(ctx, ch, exchange, key, msg)
+		if errors.Is(err, amqp.ErrClosed) {
+			p.reset(ch)
+		}
+		return err
+	})
+}
+
+// Close closes the current channel, if any.
+func (p *Publisher) Close() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.channel == nil {
+		return nil
+	}
+	ch := p.channel
+	p.channel = nil
+	return ch.Close()
+}
+
+func (p *Publisher) publish(ctx context.Context, ch *amqp.Channel, exchange, key string, msg amqp.Publishing) error {
+	if !p.Confirms {
+		return ch.PublishWithContext(ctx, exchange, key, false, false, msg)
+	}
+
+	confirm, err := ch.PublishWithDeferredConfirmWithContext(ctx, exchange, key, false, false, msg)
+	if err != nil {
+		return err
+	}
+	acked, err := confirm.WaitContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !acked {
+		return ErrNacked
+	}
+	return nil
+}
+
+func (p *Publisher) open() (*amqp.Channel, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.channel != nil && !p.channel.IsClosed() {
+		return p.channel, nil
+	}
+
+	ch, err := p.Dial()
+	if err != nil {
+		return nil, err
+	}
+	if p.Confirms {
+		if err := ch.Confirm(false); err != nil {
+			ch.Close()
+			return nil, err
+		}
+	}
+	p.channel = ch
+	return ch, nil
+}
+
+func (p *Publisher) reset(ch *amqp.Channel) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.channel == ch {
+		p.channel = nil
+	}
+}
@@ -0,0 +1,11 @@
+module github.com/sj902/breaker/breakeraws
+
+go 1.21
+
+require (
+	github.com/aws/aws-sdk-go-v2 v1.30.5
+	github.com/aws/smithy-go v1.20.4
+	github.com/sj902/breaker v0.0.0
+)
+
+replace github.com/sj902/breaker => ../
@@ -0,0 +1,128 @@
+// Package breakeraws provides an AWS SDK v2 middleware running API calls through circuit breakers.
+package breakeraws
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+
+	"github.com/sj902/breaker"
+)
+
+// Middleware runs every API call, including its retries, through the circuit
+// breaker of its key. Add it to clients with AddTo:
+//
+//	cfg.APIOptions = append(cfg.APIOptions, mw.AddTo)
+type Middleware struct {
+	// Settings are used to create the circuit breaker of every key, named after the key.
+	Settings breaker.Settings
+	// Key returns the key of the circuit breaker for a call, KeyByService if nil.
+	Key func(ctx context.Context) string
+	// IsFailure reports whether a call error counts as a failure, IsFailure if nil.
+	IsFailure func(error) bool
+
+	mutex    sync.Mutex
+	breakers map[string]*breaker.CircuitBreaker
+}
+
+// IsFailure reports whether err counts as a failure: throttling errors, 5xx
+// responses and errors without a response. Other API errors, such as a
+// missing S3 key, are caused by the request and count as successes.
+func IsFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		if _, ok := retry.DefaultThrottleErrorCodes[apiErr.ErrorCode()]; ok {
+			return true
+		}
+	}
+
+	var respErr *smithyhttp.ResponseError
+	if errors.As(err, &respErr) {
+		status := respErr.HTTPStatusCode()
+		return status >= http.StatusInternalServerError || status == http.StatusTooManyRequests
+	}
+	return true
+}
+
+// KeyByService keys circuit breakers by service, e.g. "S3".
+func KeyByService(ctx context.Context) string {
+	return awsmiddleware.GetServiceID(ctx)
+}
+
+// KeyByOperation keys circuit breakers by service and operation, e.g. "S3.GetObject".
+func KeyByOperation(ctx context.Context) string {
+	return awsmiddleware.GetServiceID(ctx) + "." + awsmiddleware.GetOperationName(ctx)
+}
+
+// AddTo adds m to the end of the initialize step of stack, after the service
+// metadata used by the keys is set.
+func (m *Middleware) AddTo(stack *middleware.Stack) error {
+	return stack.Initialize.Add(m, middleware.After)
+}
+
+func (m *Middleware) ID() string {
+	return "CircuitBreaker"
+}
+
+func (m *Middleware) HandleInitialize(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (
+	out middleware.InitializeOutput, metadata middleware.Metadata, err error,
+) {
+	done, err := m.Breaker(m.key(ctx)).AllowOutcome()
+	if err != nil {
+		return out, metadata, err
+	}
+
+	out, metadata, err = next.HandleInitialize(ctx, in)
+
+	isFailure := m.IsFailure
+	if isFailure == nil {
+		isFailure = IsFailure
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		// A canceled call says nothing about the health of the service.
+		done(breaker.OutcomeIgnored)
+	case isFailure(err):
+		done(breaker.OutcomeFailure)
+	default:
+		done(breaker.OutcomeSuccess)
+	}
+	return out, metadata, err
+}
+
+// Breaker returns the circuit breaker of key, creating it on first use.
+func (m *Middleware) Breaker(key string) *breaker.CircuitBreaker {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.breakers == nil {
+		m.breakers = make(map[string]*breaker.CircuitBreaker)
+	}
+
+	cb, ok := m.breakers[key]
+	if !ok {
+		st := m.Settings
+		st.Name = key
+		cb = breaker.NewCircuitBreaker(st)
+		m.breakers[key] = cb
+	}
+	return cb
+}
+
+func (m *Middleware) key(ctx context.Context) string {
+	if m.Key == nil {
+		return KeyByService(ctx)
+	}
+	return m.Key(ctx)
+}
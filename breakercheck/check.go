@@ -0,0 +1,249 @@
+// Package breakercheck drives circuit breakers with sequences of operations
+// under a fake clock and checks the invariants of their state machine after
+// every operation, for property tests and fuzzing:
+//
+//	func FuzzBreaker(f *testing.F) {
+//		f.Fuzz(func(t *testing.T, data []byte) {
+//			if err := breakercheck.Run(st, breakercheck.FromBytes(data)); err != nil {
+//				t.Fatal(err)
+//			}
+//		})
+//	}
+package breakercheck
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/sj902/breaker"
+	"github.com/sj902/breaker/breakermock"
+)
+
+// OpKind is the kind of an Op.
+type OpKind int
+
+const (
+	// OpSuccess runs a successful request.
+	OpSuccess OpKind = iota
+	// OpFailure runs a failing request.
+	OpFailure
+	// OpStart admits a request and leaves it in flight.
+	OpStart
+	// OpFinishSuccess completes the oldest request in flight successfully.
+	OpFinishSuccess
+	// OpFinishFailure completes the oldest request in flight with a failure.
+	OpFinishFailure
+	// OpAdvance moves the clock forward by Op.Duration.
+	OpAdvance
+	// OpTrip calls Trip.
+	OpTrip
+	// OpReset calls Reset.
+	OpReset
+	opKinds
+)
+
+// String implements stringer interface.
+func (k OpKind) String() string {
+	switch k {
+	case OpSuccess:
+		return "success"
+	case OpFailure:
+		return "failure"
+	case OpStart:
+		return "start"
+	case OpFinishSuccess:
+		return "finish success"
+	case OpFinishFailure:
+		return "finish failure"
+	case OpAdvance:
+		return "advance"
+	case OpTrip:
+		return "trip"
+	case OpReset:
+		return "reset"
+	default:
+		return fmt.Sprintf("unknown op: %d", k)
+	}
+}
+
+// Op is an operation on a circuit breaker.
+type Op struct {
+	Kind     OpKind
+	Duration time.Duration
+}
+
+// String implements stringer interface.
+func (op Op) String() string {
+	if op.Kind == OpAdvance {
+		return fmt.Sprintf("advance %v", op.Duration)
+	}
+	return op.Kind.String()
+}
+
+// maxAdvance bounds the clock moves of generated operations.
+const maxAdvance = 2 * time.Minute
+
+// RandomOps returns n random operations.
+func RandomOps(rnd *rand.Rand, n int) []Op {
+	ops := make([]Op, n)
+	for i := range ops {
+		ops[i] = Op{Kind: OpKind(rnd.Intn(int(opKinds)))}
+		if ops[i].Kind == OpAdvance {
+			ops[i].Duration = time.Duration(rnd.Int63n(int64(maxAdvance)))
+		}
+	}
+	return ops
+}
+
+// FromBytes decodes operations from fuzzer input, two bytes per operation.
+func FromBytes(data []byte) []Op {
+	ops := make([]Op, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		op := Op{Kind: OpKind(int(data[i]) % int(opKinds))}
+		if op.Kind == OpAdvance {
+			op.Duration = time.Duration(data[i+1]) * maxAdvance / 255
+		}
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+// Transition is a state change observed while running operations.
+type Transition struct {
+	Time time.Time
+	From breaker.State
+	To   breaker.State
+}
+
+// Violation is an invariant broken by a sequence of operations.
+type Violation struct {
+	// Ops are the operations run, the last one broke the invariant.
+	Ops    []Op
+	Before breaker.Inspection
+	After  breaker.Inspection
+	Reason string
+}
+
+func (v *Violation) Error() string {
+	ops := make([]string, len(v.Ops))
+	for i, op := range v.Ops {
+		ops[i] = op.String()
+	}
+	return fmt.Sprintf("%s after [%s]: before %+v, after %+v", v.Reason, strings.Join(ops, ", "), v.Before, v.After)
+}
+
+// Run runs ops on a circuit breaker created from st with a fake clock and
+// returns the first invariant violated, as a *Violation, or nil.
+//
+// The state must only change along the edges of the state machine, and open
+// circuits must not half-open before their expiry. Counts must be cleared on
+// every new generation, or on every transition when st has a window, and
+// must stay consistent. Half-open circuits must not admit more than
+// MaxRequests requests per generation unless MaxConcurrentProbes is set.
+func Run(st breaker.Settings, ops []Op) error {
+	clock := breakermock.NewClock(time.Unix(0, 0).UTC())
+	st.Clock = clock
+
+	var transitions []Transition
+	onStateChange := st.OnStateChange
+	st.OnStateChange = func(name string, from breaker.State, to breaker.State) {
+		transitions = append(transitions, Transition{Time: clock.Now(), From: from, To: to})
+		if onStateChange != nil {
+			onStateChange(name, from, to)
+		}
+	}
+
+	cb := breaker.NewCircuitBreaker(st)
+	var inFlight []func(success bool)
+	before := cb.Inspect()
+	for i, op := range ops {
+		transitions = transitions[:0]
+		admitted := false
+
+		switch op.Kind {
+		case OpSuccess, OpFailure:
+			if done, err := cb.Allow(); err == nil {
+				admitted = true
+				done(op.Kind == OpSuccess)
+			}
+		case OpStart:
+			if done, err := cb.Allow(); err == nil {
+				admitted = true
+				inFlight = append(inFlight, done)
+			}
+		case OpFinishSuccess, OpFinishFailure:
+			if len(inFlight) > 0 {
+				inFlight[0](op.Kind == OpFinishSuccess)
+				inFlight = inFlight[1:]
+			}
+		case OpAdvance:
+			clock.Advance(op.Duration)
+			cb.State()
+		case OpTrip:
+			cb.Trip()
+		case OpReset:
+			cb.Reset()
+		}
+
+		after := cb.Inspect()
+		if reason := check(st, op, admitted, before, after, transitions, len(inFlight)); reason != "" {
+			return &Violation{Ops: ops[:i+1], Before: before, After: after, Reason: reason}
+		}
+		before = after
+	}
+	return nil
+}
+
+// check returns the invariant broken by op, if any.
+func check(st breaker.Settings, op Op, admitted bool, before, after breaker.Inspection, transitions []Transition, inFlight int) string {
+	state := before.State
+	for _, t := range transitions {
+		if t.From != state {
+			return fmt.Sprintf("transition from %s while %s", t.From, state)
+		}
+		switch {
+		case t.From == breaker.StateClosed && t.To == breaker.StateHalfOpen:
+			return "closed circuit half-opened"
+		case t.From == breaker.StateOpen && t.To == breaker.StateHalfOpen && t.Time.Before(before.Expiry):
+			return fmt.Sprintf("open circuit half-opened at %v before its expiry %v", t.Time, before.Expiry)
+		case t.From == breaker.StateOpen && t.To == breaker.StateClosed && op.Kind != OpReset:
+			return "open circuit closed without Reset"
+		}
+		state = t.To
+	}
+	if state != after.State {
+		return fmt.Sprintf("state %s without transition from %s", after.State, state)
+	}
+
+	// Windows keep their totals over the generations of the closed state and
+	// drop old outcomes while the consecutive counts stay.
+	windowed := st.WindowSize > 0 || st.RollingWindow > 0
+	cleared := after.Generation != before.Generation && (!windowed || len(transitions) > 0)
+
+	c := after.Counts
+	switch {
+	case after.Generation < before.Generation:
+		return "generation went backwards"
+	case len(transitions) > 0 && after.Generation == before.Generation:
+		return "transition without a new generation"
+	case cleared && c.Requests > 1:
+		return fmt.Sprintf("%d requests counted right after a new generation", c.Requests)
+	case !windowed && c.TotalSuccess+c.TotalFail > c.Requests:
+		return "more outcomes than requests"
+	case !windowed && (c.ConsecutiveSuccess > c.TotalSuccess || c.ConsecutiveFail > c.TotalFail):
+		return "more consecutive than total outcomes"
+	case c.ConsecutiveSuccess > 0 && c.ConsecutiveFail > 0:
+		return "consecutive successes and failures at once"
+	case after.InFlight != inFlight:
+		return fmt.Sprintf("%d requests in flight, expected %d", after.InFlight, inFlight)
+	case after.State == breaker.StateHalfOpen && st.MaxConcurrentProbes == 0 && !st.Disabled && c.Requests > after.MaxRequests:
+		return fmt.Sprintf("half-open circuit admitted %d requests, more than %d", c.Requests, after.MaxRequests)
+	case after.State == breaker.StateOpen && admitted && !st.Disabled && after.Generation == before.Generation:
+		return "open circuit admitted a request"
+	case after.State == breaker.StateHalfOpen && !after.Expiry.IsZero():
+		return "half-open circuit with an expiry"
+	}
+	return ""
+}
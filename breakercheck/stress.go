@@ -0,0 +1,81 @@
+package breakercheck
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/sj902/breaker"
+	"github.com/sj902/breaker/breakermock"
+)
+
+var errFailure = errors.New("failure")
+
+// Stress runs n random operations from each of goroutines goroutines on one
+// circuit breaker created from st, to find data races with the race detector
+// and illegal transitions under concurrency. It returns the first transition
+// off the edges of the state machine, or requests left in flight at the end.
+func Stress(st breaker.Settings, goroutines, n int, seed int64) error {
+	clock := breakermock.NewClock(time.Unix(0, 0).UTC())
+	st.Clock = clock
+
+	// OnStateChange runs with the breaker locked, so transitions are recorded in order.
+	var transitions []Transition
+	onStateChange := st.OnStateChange
+	st.OnStateChange = func(name string, from breaker.State, to breaker.State) {
+		transitions = append(transitions, Transition{Time: clock.Now(), From: from, To: to})
+		if onStateChange != nil {
+			onStateChange(name, from, to)
+		}
+	}
+	cb := breaker.NewCircuitBreaker(st)
+
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(rnd *rand.Rand) {
+			defer wg.Done()
+			for _, op := range RandomOps(rnd, n) {
+				switch op.Kind {
+				case OpSuccess, OpFailure, OpStart:
+					cb.Do(func() error {
+						if op.Kind == OpFailure {
+							return errFailure
+						}
+						return nil
+					})
+				case OpAdvance:
+					clock.Advance(op.Duration / 100)
+				case OpTrip:
+					cb.Trip()
+				case OpReset:
+					cb.Reset()
+				default:
+					cb.State()
+					cb.Counts()
+					cb.Metrics()
+					cb.Snapshot()
+				}
+			}
+		}(rand.New(rand.NewSource(seed + int64(g))))
+	}
+	wg.Wait()
+
+	state := breaker.StateClosed
+	for i, t := range transitions {
+		if t.From != state {
+			return fmt.Errorf("transition %d from %s while %s", i, t.From, state)
+		}
+		if t.From == breaker.StateClosed && t.To == breaker.StateHalfOpen {
+			return fmt.Errorf("transition %d: closed circuit half-opened", i)
+		}
+		state = t.To
+	}
+
+	if in := cb.Inspect(); in.InFlight != 0 {
+		return fmt.Errorf("%d requests left in flight", in.InFlight)
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+module github.com/sj902/breaker/breakerchi
+
+go 1.21
+
+require (
+	github.com/go-chi/chi/v5 v5.2.3
+	github.com/sj902/breaker v0.0.0
+)
+
+replace github.com/sj902/breaker => ../
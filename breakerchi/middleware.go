@@ -0,0 +1,36 @@
+// Package breakerchi keys circuit breakers by chi route patterns.
+package breakerchi
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/sj902/breaker"
+	"github.com/sj902/breaker/breakerhttp"
+)
+
+// Middleware returns a chi middleware keeping one circuit breaker per route
+// pattern, all created from st. It can be installed with Router.Use on the
+// root router, before the route is matched.
+func Middleware(st breaker.Settings) func(http.Handler) http.Handler {
+	k := &breakerhttp.Keyed{Settings: st, Key: RoutePattern}
+	return k.Middleware
+}
+
+// RoutePattern returns the pattern of the chi route matching r, e.g.
+// "/users/{id}", or "" if no route matches. When called before chi has
+// finished routing, the pattern is resolved by matching r against the routes
+// of the router.
+func RoutePattern(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil || rctx.Routes == nil {
+		return ""
+	}
+
+	tctx := chi.NewRouteContext()
+	if !rctx.Routes.Match(tctx, r.Method, r.URL.Path) {
+		return ""
+	}
+	return tctx.RoutePattern()
+}
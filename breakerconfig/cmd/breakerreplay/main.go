@@ -0,0 +1,128 @@
+// Command breakerreplay replays recorded calls to a dependency against
+// candidate circuit breaker settings and reports when each of them would have
+// tripped and recovered, to pick thresholds from real traffic.
+//
+// The candidates are the breakers of a breakerconfig document, on top of its
+// defaults, and optionally the presets of the breaker package. The calls are
+// read as JSON lines or CSV, see breakersim.ReadRecords.
+//
+// Usage:
+//
+//	breakerreplay [-config candidates.yaml] [-presets] [-v] calls.csv
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/sj902/breaker"
+	"github.com/sj902/breaker/breakerconfig"
+	"github.com/sj902/breaker/breakersim"
+)
+
+type candidate struct {
+	name     string
+	settings breaker.Settings
+}
+
+func main() {
+	config := flag.String("config", "", "breakerconfig document whose breakers are the candidates")
+	presets := flag.Bool("presets", false, "also replay the presets")
+	verbose := flag.Bool("v", false, "print the state timeline of every candidate")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: breakerreplay [flags] calls-file")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(flag.Arg(0), *config, *presets, *verbose, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "breakerreplay:", err)
+		os.Exit(1)
+	}
+}
+
+func run(path, config string, presets, verbose bool, out io.Writer) error {
+	candidates, err := candidatesOf(config, presets)
+	if err != nil {
+		return err
+	}
+	if len(candidates) == 0 {
+		return fmt.Errorf("no candidates, set -config or -presets")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	records, err := breakersim.ReadRecords(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	results := make([]breakersim.Result, len(candidates))
+	for i, c := range candidates {
+		results[i] = breakersim.Replay(c.settings, records)
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "CANDIDATE\tTRIPS\tOPEN\tREJECTED\tFAILURES SPARED\tSUCCESSES REJECTED\tFAILURES")
+	for i, c := range candidates {
+		r := results[i]
+		trips := 0
+		for _, t := range r.Timeline {
+			if t.From == breaker.StateClosed && t.To == breaker.StateOpen {
+				trips++
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%v\t%d\t%d\t%d\t%d\n",
+			c.name, trips, r.TimeIn[breaker.StateOpen], r.Rejected, r.FailuresSpared, r.SuccessesRejected, r.Failures)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if verbose {
+		for i, c := range candidates {
+			fmt.Fprintf(out, "\n%s\n%s", c.name, results[i])
+		}
+	}
+	return nil
+}
+
+func candidatesOf(config string, presets bool) ([]candidate, error) {
+	var candidates []candidate
+	if config != "" {
+		cfg, err := breakerconfig.Load(config)
+		if err != nil {
+			return nil, err
+		}
+
+		names := make([]string, 0, len(cfg.Breakers))
+		for name := range cfg.Breakers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			candidates = append(candidates, candidate{name, cfg.Settings(name, breaker.Settings{})})
+		}
+	}
+
+	if presets {
+		candidates = append(candidates,
+			candidate{"preset-http-client", breaker.PresetHTTPClient()},
+			candidate{"preset-database", breaker.PresetDatabase()},
+			candidate{"preset-aggressive", breaker.PresetAggressive()},
+		)
+	}
+	return candidates, nil
+}
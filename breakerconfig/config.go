@@ -0,0 +1,225 @@
+// Package breakerconfig builds circuit breakers from YAML or JSON documents,
+// so that their tuning can live in configuration instead of code:
+//
+//	defaults:
+//	  timeout: 30s
+//	  failure_rate_threshold: 0.5
+//	  minimum_requests: 20
+//	breakers:
+//	  payments:
+//	    timeout: 10s
+//	    max_requests: 3
+package breakerconfig
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/sj902/breaker"
+)
+
+// Config is a configuration document.
+type Config struct {
+	// Defaults apply to every circuit breaker.
+	Defaults Breaker `yaml:"defaults" json:"defaults"`
+	// Breakers holds the settings of named circuit breakers, on top of Defaults.
+	Breakers map[string]Breaker `yaml:"breakers" json:"breakers"`
+	// MaxBreakers bounds the registry, see breaker.NewBoundedRegistry.
+	MaxBreakers int `yaml:"max_breakers" json:"max_breakers"`
+}
+
+// Breaker holds the settings of a circuit breaker. Unset fields keep the value
+// they are applied on.
+type Breaker struct {
+	Timeout               *Duration `yaml:"timeout" json:"timeout"`
+	Interval              *Duration `yaml:"interval" json:"interval"`
+	MaxRequests           *int      `yaml:"max_requests" json:"max_requests"`
+	SingleProbe           *bool     `yaml:"single_probe" json:"single_probe"`
+	MaxConcurrent         *int      `yaml:"max_concurrent" json:"max_concurrent"`
+	MaxQueue              *int      `yaml:"max_queue" json:"max_queue"`
+	MaxWait               *Duration `yaml:"max_wait" json:"max_wait"`
+	SuccessThreshold      *int      `yaml:"success_threshold" json:"success_threshold"`
+	WindowSize            *int      `yaml:"window_size" json:"window_size"`
+	RollingWindow         *Duration `yaml:"rolling_window" json:"rolling_window"`
+	RollingBuckets        *int      `yaml:"rolling_buckets" json:"rolling_buckets"`
+	FailureRateThreshold  *float64  `yaml:"failure_rate_threshold" json:"failure_rate_threshold"`
+	MinimumRequests       *int      `yaml:"minimum_requests" json:"minimum_requests"`
+	SlowCallThreshold     *Duration `yaml:"slow_call_threshold" json:"slow_call_threshold"`
+	SlowCallRateThreshold *float64  `yaml:"slow_call_rate_threshold" json:"slow_call_rate_threshold"`
+	Disabled              *bool     `yaml:"disabled" json:"disabled"`
+	CacheTTL              *Duration `yaml:"cache_ttl" json:"cache_ttl"`
+	CacheSize             *int      `yaml:"cache_size" json:"cache_size"`
+}
+
+// Duration is a time.Duration written as a string, e.g. "1m30s".
+type Duration time.Duration
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// Parse parses a YAML or JSON document. Unknown fields are errors, so that
+// typos don't go unnoticed.
+func Parse(data []byte) (*Config, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+
+	var c Config
+	if err := dec.Decode(&c); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("breakerconfig: %w", err)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Load reads and parses the document at path.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Validate checks that the settings are in range and don't conflict with
+// each other.
+func (c *Config) Validate() error {
+	if c.MaxBreakers < 0 {
+		return errors.New("breakerconfig: max_breakers must not be negative")
+	}
+	if err := c.Defaults.validate(); err != nil {
+		return fmt.Errorf("breakerconfig: defaults: %w", err)
+	}
+	for name, b := range c.Breakers {
+		if err := b.validate(); err != nil {
+			return fmt.Errorf("breakerconfig: breaker %q: %w", name, err)
+		}
+	}
+	return c.ValidateSettings(breaker.Settings{})
+}
+
+// ValidateSettings checks the settings the defaults and every configured
+// circuit breaker end up with on base, see breaker.Settings.Validate.
+func (c *Config) ValidateSettings(base breaker.Settings) error {
+	if err := c.Defaults.Apply(base).Validate(); err != nil {
+		return fmt.Errorf("breakerconfig: defaults: %w", err)
+	}
+	for name := range c.Breakers {
+		if err := c.Settings(name, base).Validate(); err != nil {
+			return fmt.Errorf("breakerconfig: breaker %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (b Breaker) validate() error {
+	for field, d := range map[string]*Duration{
+		"timeout":             b.Timeout,
+		"interval":            b.Interval,
+		"max_wait":            b.MaxWait,
+		"rolling_window":      b.RollingWindow,
+		"slow_call_threshold": b.SlowCallThreshold,
+		"cache_ttl":           b.CacheTTL,
+	} {
+		if d != nil && *d < 0 {
+			return fmt.Errorf("%s must not be negative", field)
+		}
+	}
+	for field, n := range map[string]*int{
+		"max_requests":      b.MaxRequests,
+		"max_concurrent":    b.MaxConcurrent,
+		"max_queue":         b.MaxQueue,
+		"success_threshold": b.SuccessThreshold,
+		"window_size":       b.WindowSize,
+		"rolling_buckets":   b.RollingBuckets,
+		"minimum_requests":  b.MinimumRequests,
+		"cache_size":        b.CacheSize,
+	} {
+		if n != nil && *n < 0 {
+			return fmt.Errorf("%s must not be negative", field)
+		}
+	}
+	for field, r := range map[string]*float64{
+		"failure_rate_threshold":   b.FailureRateThreshold,
+		"slow_call_rate_threshold": b.SlowCallRateThreshold,
+	} {
+		if r != nil && (*r < 0 || *r > 1) {
+			return fmt.Errorf("%s must be between 0 and 1", field)
+		}
+	}
+	return nil
+}
+
+// Apply returns st with the fields set in b.
+func (b Breaker) Apply(st breaker.Settings) breaker.Settings {
+	setDuration(&st.OpenTimeout, b.Timeout)
+	setDuration(&st.Interval, b.Interval)
+	set(&st.MaxRequests, b.MaxRequests)
+	set(&st.SingleProbe, b.SingleProbe)
+	set(&st.MaxConcurrent, b.MaxConcurrent)
+	set(&st.MaxQueue, b.MaxQueue)
+	setDuration(&st.MaxWait, b.MaxWait)
+	set(&st.SuccessThreshold, b.SuccessThreshold)
+	set(&st.WindowSize, b.WindowSize)
+	setDuration(&st.RollingWindow, b.RollingWindow)
+	set(&st.RollingBuckets, b.RollingBuckets)
+	set(&st.FailureRateThreshold, b.FailureRateThreshold)
+	set(&st.MinimumRequests, b.MinimumRequests)
+	setDuration(&st.SlowCallThreshold, b.SlowCallThreshold)
+	set(&st.SlowCallRateThreshold, b.SlowCallRateThreshold)
+	set(&st.Disabled, b.Disabled)
+	setDuration(&st.CacheTTL, b.CacheTTL)
+	set(&st.CacheSize, b.CacheSize)
+	return st
+}
+
+func set[T any](dst *T, v *T) {
+	if v != nil {
+		*dst = *v
+	}
+}
+
+func setDuration(dst *time.Duration, v *Duration) {
+	if v != nil {
+		*dst = time.Duration(*v)
+	}
+}
+
+// Settings returns the settings of the circuit breaker named name: base, for
+// what only code can set such as Logger or Classifier, with the defaults and
+// the settings of name applied.
+func (c *Config) Settings(name string, base breaker.Settings) breaker.Settings {
+	st := c.Defaults.Apply(base)
+	st = c.Breakers[name].Apply(st)
+	st.Name = name
+	return st
+}
+
+// Registry returns a registry holding the configured circuit breakers and
+// creating the others from base with the defaults applied.
+func (c *Config) Registry(base breaker.Settings) *breaker.Registry {
+	r := breaker.NewBoundedRegistry(c.Defaults.Apply(base), c.MaxBreakers)
+	for name := range c.Breakers {
+		r.Add(breaker.NewCircuitBreaker(c.Settings(name, base)))
+	}
+	return r
+}
@@ -0,0 +1,13 @@
+module github.com/sj902/breaker/breakerconfig
+
+go 1.21
+
+require (
+	github.com/fsnotify/fsnotify v1.7.0
+	github.com/sj902/breaker v0.0.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require golang.org/x/sys v0.4.0 // indirect
+
+replace github.com/sj902/breaker => ../
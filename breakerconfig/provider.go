@@ -0,0 +1,152 @@
+package breakerconfig
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/sj902/breaker"
+)
+
+const defaultPollInterval = 30 * time.Second
+
+// ConfigProvider supplies the settings of circuit breakers at runtime, e.g.
+// thresholds and the disabled flag from a feature flag service.
+type ConfigProvider interface {
+	// Config returns the settings of the circuit breaker named name and
+	// whether the provider has any.
+	Config(ctx context.Context, name string) (Breaker, bool, error)
+}
+
+// Subscriber is implemented by providers pushing changes. Subscribe calls f
+// with the settings of name whenever they change until unsubscribe is called.
+type Subscriber interface {
+	Subscribe(name string, f func(b Breaker, ok bool)) (unsubscribe func())
+}
+
+// ProviderFunc adapts a function to a ConfigProvider.
+type ProviderFunc func(ctx context.Context, name string) (Breaker, bool, error)
+
+// Config implements ConfigProvider.
+func (f ProviderFunc) Config(ctx context.Context, name string) (Breaker, bool, error) {
+	return f(ctx, name)
+}
+
+// Dynamic drives circuit breakers from a ConfigProvider. The provided settings
+// are applied on top of the static ones; when the provider has none for a
+// breaker, fails or provides invalid settings, the breaker falls back to its
+// static settings.
+type Dynamic struct {
+	Provider ConfigProvider
+	// Static is the configuration the provided settings apply on. If nil,
+	// Base alone is used.
+	Static *Config
+	// Base holds the settings only code can set, as in Config.Settings.
+	Base breaker.Settings
+	// Interval is how often providers not implementing Subscriber are polled,
+	// defaults to 30s.
+	Interval time.Duration
+	// OnError is called with the errors of the provider.
+	OnError func(name string, err error)
+}
+
+// Watch subscribes to the settings of cb, or polls them, and applies them
+// with UpdateSettings until ctx is done.
+func (d *Dynamic) Watch(ctx context.Context, cb *breaker.CircuitBreaker) {
+	name := cb.Name()
+	var current *Breaker
+	apply := func(b Breaker, ok bool) {
+		if ok {
+			err := b.validate()
+			if err == nil {
+				err = b.Apply(d.static(name)).Validate()
+			}
+			if err != nil {
+				d.fail(name, err)
+				ok = false
+			}
+		}
+		if !ok {
+			b = Breaker{}
+		}
+		if current != nil && reflect.DeepEqual(*current, b) {
+			return
+		}
+
+		current = &b
+		cb.UpdateSettings(b.Apply(d.static(name)))
+	}
+
+	if s, ok := d.Provider.(Subscriber); ok {
+		var (
+			mutex  sync.Mutex
+			latest func()
+		)
+		updated := make(chan struct{}, 1)
+		unsubscribe := s.Subscribe(name, func(b Breaker, ok bool) {
+			// Keep only the latest update.
+			mutex.Lock()
+			latest = func() { apply(b, ok) }
+			mutex.Unlock()
+
+			select {
+			case updated <- struct{}{}:
+			default:
+			}
+		})
+		defer unsubscribe()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-updated:
+				mutex.Lock()
+				f := latest
+				mutex.Unlock()
+				f()
+			}
+		}
+	}
+
+	interval := d.Interval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		b, ok, err := d.Provider.Config(ctx, name)
+		if err != nil {
+			d.fail(name, err)
+			ok = false
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		apply(b, ok)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *Dynamic) static(name string) breaker.Settings {
+	if d.Static != nil {
+		return d.Static.Settings(name, d.Base)
+	}
+	st := d.Base
+	st.Name = name
+	return st
+}
+
+func (d *Dynamic) fail(name string, err error) {
+	if d.OnError != nil {
+		d.OnError(name, err)
+	}
+}
@@ -0,0 +1,181 @@
+package breakerconfig
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/sj902/breaker"
+)
+
+var errNoFile = errors.New("breakerconfig: watcher has no file")
+
+// debounce is how long the watcher waits for writes to a file to settle.
+const debounce = 100 * time.Millisecond
+
+// Watcher keeps the circuit breakers of a registry in line with a
+// configuration file. Every change is applied with UpdateSettings, which
+// sends an EventSettingsChange; an invalid file is rejected as a whole and
+// the breakers keep their settings.
+type Watcher struct {
+	path    string
+	base    breaker.Settings
+	reg     *breaker.Registry
+	onError func(err error)
+
+	mutex  sync.Mutex
+	config *Config
+}
+
+// NewWatcher loads the configuration file at path and returns a watcher
+// whose registry holds the configured circuit breakers, built on base as with
+// Config.Registry. Call Run to start watching.
+func NewWatcher(path string, base breaker.Settings) (*Watcher, error) {
+	c, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.ValidateSettings(base); err != nil {
+		return nil, err
+	}
+
+	return &Watcher{
+		path:   path,
+		base:   base,
+		reg:    c.Registry(base),
+		config: c,
+	}, nil
+}
+
+// NewConfigWatcher returns a watcher like NewWatcher for a configuration
+// kept elsewhere than in a file, e.g. in a key-value store. Its changes are
+// fed to the watcher with Apply; Run and Reload fail.
+func NewConfigWatcher(c *Config, base breaker.Settings) (*Watcher, error) {
+	if err := c.ValidateSettings(base); err != nil {
+		return nil, err
+	}
+
+	return &Watcher{
+		base:   base,
+		reg:    c.Registry(base),
+		config: c,
+	}, nil
+}
+
+// Registry returns the registry kept up to date by the watcher.
+func (w *Watcher) Registry() *breaker.Registry {
+	return w.reg
+}
+
+// Config returns the configuration currently applied.
+func (w *Watcher) Config() *Config {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.config
+}
+
+// OnError sets a function called with the errors of reloads, e.g. to log
+// invalid files. It must be called before Run.
+func (w *Watcher) OnError(f func(err error)) {
+	w.onError = f
+}
+
+// Run watches the configuration file until ctx is done and applies its
+// changes. The directory of the file is watched, so that files replaced by
+// editors or by Kubernetes config map updates are picked up too.
+func (w *Watcher) Run(ctx context.Context) error {
+	if w.path == "" {
+		return errNoFile
+	}
+
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer fw.Close()
+
+	if err := fw.Add(filepath.Dir(w.path)); err != nil {
+		return err
+	}
+
+	path := filepath.Clean(w.path)
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev := <-fw.Events:
+			// Config maps swap a symlinked directory, so any change in it may matter.
+			if filepath.Clean(ev.Name) == path || ev.Op&fsnotify.Create != 0 {
+				timer.Reset(debounce)
+			}
+		case err := <-fw.Errors:
+			w.fail(err)
+		case <-timer.C:
+			if err := w.Reload(); err != nil {
+				w.fail(err)
+			}
+		}
+	}
+}
+
+func (w *Watcher) fail(err error) {
+	if w.onError != nil {
+		w.onError(err)
+	}
+}
+
+// Reload reads the configuration file and applies it if it is valid.
+func (w *Watcher) Reload() error {
+	if w.path == "" {
+		return errNoFile
+	}
+
+	c, err := Load(w.path)
+	if err != nil {
+		return err
+	}
+	return w.Apply(c)
+}
+
+// Apply updates the circuit breakers whose settings differ between the
+// current configuration and c, adds the newly configured ones and makes c the
+// current configuration. If c is invalid on the base settings of the watcher
+// nothing is changed and the error is returned.
+func (w *Watcher) Apply(c *Config) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	if err := c.ValidateSettings(w.base); err != nil {
+		return err
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	old := w.config
+	w.reg.SetDefaults(c.Defaults.Apply(w.base))
+
+	defaultsChanged := !reflect.DeepEqual(old.Defaults, c.Defaults)
+	for _, cb := range w.reg.Breakers() {
+		name := cb.Name()
+		if defaultsChanged || !reflect.DeepEqual(old.Breakers[name], c.Breakers[name]) {
+			cb.UpdateSettings(c.Settings(name, w.base))
+		}
+	}
+	for name := range c.Breakers {
+		if _, ok := w.reg.Lookup(name); !ok {
+			w.reg.Add(breaker.NewCircuitBreaker(c.Settings(name, w.base)))
+		}
+	}
+
+	w.config = c
+	return nil
+}
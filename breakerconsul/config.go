@@ -0,0 +1,101 @@
+package breakerconsul
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+
+	"github.com/sj902/breaker"
+	"github.com/sj902/breaker/breakerconfig"
+)
+
+// Config reads the breakerconfig document, YAML or JSON, stored at a key of
+// the Consul KV store, so that the tuning of a fleet's breakers can be changed
+// in one place:
+//
+//	conf := &breakerconsul.Config{KV: client.KV(), Key: "myservice/breakers.yaml"}
+//	w, err := conf.NewWatcher(ctx, base)
+//	go conf.Watch(ctx, w)
+type Config struct {
+	KV  *api.KV
+	Key string
+	// OnError, if non-nil, is called with the errors of Watch, e.g. to log
+	// invalid documents.
+	OnError func(err error)
+}
+
+// Load reads and parses the document.
+func (c *Config) Load(ctx context.Context) (*breakerconfig.Config, error) {
+	conf, _, err := c.get(ctx, &api.QueryOptions{RequireConsistent: true})
+	return conf, err
+}
+
+// NewWatcher loads the document and returns a watcher whose registry holds
+// the configured circuit breakers, built on base, see
+// breakerconfig.NewConfigWatcher. Call Watch to keep it up to date.
+func (c *Config) NewWatcher(ctx context.Context, base breaker.Settings) (*breakerconfig.Watcher, error) {
+	conf, err := c.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return breakerconfig.NewConfigWatcher(conf, base)
+}
+
+// Watch applies the changes of the document to w with Apply until ctx is
+// done. It uses blocking queries, so that changes are applied as soon as
+// Consul reports them. An invalid document is rejected as a whole and passed
+// to OnError.
+func (c *Config) Watch(ctx context.Context, w *breakerconfig.Watcher) {
+	var index uint64
+	for ctx.Err() == nil {
+		conf, meta, err := c.get(ctx, &api.QueryOptions{WaitIndex: index})
+		if meta == nil {
+			if ctx.Err() != nil {
+				return
+			}
+			c.fail(err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		if meta.LastIndex != index {
+			if err == nil {
+				err = w.Apply(conf)
+			}
+			c.fail(err)
+		}
+
+		// Start over when the index goes backwards, e.g. after a snapshot restore.
+		if meta.LastIndex < index {
+			index = 0
+		} else {
+			index = meta.LastIndex
+		}
+	}
+}
+
+func (c *Config) fail(err error) {
+	if err != nil && c.OnError != nil {
+		c.OnError(err)
+	}
+}
+
+// get reads and parses the document. The query metadata is nil if Consul
+// could not be queried.
+func (c *Config) get(ctx context.Context, opts *api.QueryOptions) (*breakerconfig.Config, *api.QueryMeta, error) {
+	pair, meta, err := c.KV.Get(c.Key, opts.WithContext(ctx))
+	if err != nil {
+		return nil, nil, err
+	}
+	if pair == nil {
+		return nil, meta, fmt.Errorf("breakerconsul: key %q not found", c.Key)
+	}
+
+	conf, err := breakerconfig.Parse(pair.Value)
+	return conf, meta, err
+}
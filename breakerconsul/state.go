@@ -0,0 +1,135 @@
+// Package breakerconsul provides a Consul KV backend for the distributed mode
+// of circuit breakers and for their configuration.
+package breakerconsul
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+
+	"github.com/sj902/breaker"
+)
+
+// State is a breaker.SharedState keeping one JSON snapshot per circuit
+// breaker in the Consul KV store, updated by check-and-set writes:
+//
+//	st.SharedState = &breakerconsul.State{KV: client.KV(), Prefix: "myservice/breakers/"}
+type State struct {
+	KV *api.KV
+	// Prefix is prepended to the breaker name to form the key.
+	Prefix string
+}
+
+var _ breaker.SharedState = &State{}
+
+// Load implements breaker.SharedState.
+func (s *State) Load(ctx context.Context, name string) (breaker.Snapshot, bool, error) {
+	snap, index, err := s.get(ctx, s.Prefix+name)
+	return snap, index != 0, err
+}
+
+// Add implements breaker.SharedState.
+func (s *State) Add(ctx context.Context, name string, generation int, delta breaker.Counts) (breaker.Snapshot, error) {
+	key := s.Prefix + name
+	for {
+		snap, index, err := s.get(ctx, key)
+		if err != nil {
+			return breaker.Snapshot{}, err
+		}
+		if index == 0 {
+			snap.Name = name
+			snap.State = breaker.StateClosed
+		}
+		if snap.Generation != generation {
+			return snap, nil
+		}
+
+		snap.Counts.Merge(delta)
+		ok, err := s.put(ctx, key, index, snap)
+		if err != nil || ok {
+			return snap, err
+		}
+	}
+}
+
+// CompareAndSwap implements breaker.SharedState.
+func (s *State) CompareAndSwap(ctx context.Context, name string, old int, snap breaker.Snapshot) (bool, error) {
+	key := s.Prefix + name
+	for {
+		cur, index, err := s.get(ctx, key)
+		if err != nil || cur.Generation != old {
+			return false, err
+		}
+
+		ok, err := s.put(ctx, key, index, snap)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+}
+
+// Watch calls Sync on the circuit breakers whose key changes until ctx is
+// done. It uses blocking queries, so that breakers adopt the trips of other
+// instances as soon as Consul reports them.
+func (s *State) Watch(ctx context.Context, cbs ...*breaker.CircuitBreaker) {
+	byName := make(map[string]*breaker.CircuitBreaker, len(cbs))
+	for _, cb := range cbs {
+		byName[cb.Name()] = cb
+	}
+
+	var index uint64
+	for ctx.Err() == nil {
+		opts := (&api.QueryOptions{WaitIndex: index}).WithContext(ctx)
+		pairs, meta, err := s.KV.List(s.Prefix, opts)
+		if err != nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		for _, pair := range pairs {
+			if pair.ModifyIndex <= index {
+				continue
+			}
+			if cb, ok := byName[strings.TrimPrefix(pair.Key, s.Prefix)]; ok {
+				cb.Sync()
+			}
+		}
+
+		// Start over when the index goes backwards, e.g. after a snapshot restore.
+		if meta.LastIndex < index {
+			index = 0
+		} else {
+			index = meta.LastIndex
+		}
+	}
+}
+
+// get returns the snapshot at key and its modify index, 0 if there is none.
+func (s *State) get(ctx context.Context, key string) (breaker.Snapshot, uint64, error) {
+	pair, _, err := s.KV.Get(key, (&api.QueryOptions{RequireConsistent: true}).WithContext(ctx))
+	if err != nil || pair == nil {
+		return breaker.Snapshot{}, 0, err
+	}
+
+	var snap breaker.Snapshot
+	err = json.Unmarshal(pair.Value, &snap)
+	return snap, pair.ModifyIndex, err
+}
+
+// put writes snap to key if it is still at modify index index.
+func (s *State) put(ctx context.Context, key string, index uint64, snap breaker.Snapshot) (bool, error) {
+	snap.Counts.Buckets = nil
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return false, err
+	}
+
+	ok, _, err := s.KV.CAS(&api.KVPair{Key: key, Value: data, ModifyIndex: index}, (&api.WriteOptions{}).WithContext(ctx))
+	return ok, err
+}
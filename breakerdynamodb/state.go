@@ -0,0 +1,146 @@
+// Package breakerdynamodb provides a DynamoDB backend for the distributed mode of circuit breakers.
+package breakerdynamodb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/sj902/breaker"
+)
+
+// State is a breaker.SharedState keeping one item per circuit breaker in a
+// DynamoDB table, updated by conditional writes. The table needs a string
+// partition key named "name"; items hold the JSON snapshot in "snapshot" and
+// a version number in "version":
+//
+//	st.SharedState = &breakerdynamodb.State{Client: dynamodb.NewFromConfig(cfg), Table: "breakers", TTL: 24 * time.Hour}
+type State struct {
+	Client *dynamodb.Client
+	Table  string
+	// TTL sets the "expires" attribute of items to the time of their last
+	// write plus TTL, in Unix seconds. Enable time to live on that attribute
+	// so that the items of breakers no longer used expire. Zero omits it.
+	TTL time.Duration
+}
+
+var _ breaker.SharedState = &State{}
+
+// Load implements breaker.SharedState.
+func (s *State) Load(ctx context.Context, name string) (breaker.Snapshot, bool, error) {
+	snap, version, err := s.get(ctx, name)
+	return snap, version != 0, err
+}
+
+// Add implements breaker.SharedState.
+func (s *State) Add(ctx context.Context, name string, generation int, delta breaker.Counts) (breaker.Snapshot, error) {
+	for {
+		snap, version, err := s.get(ctx, name)
+		if err != nil {
+			return breaker.Snapshot{}, err
+		}
+		if version == 0 {
+			snap.Name = name
+			snap.State = breaker.StateClosed
+		}
+		if snap.Generation != generation {
+			return snap, nil
+		}
+
+		snap.Counts.Merge(delta)
+		ok, err := s.put(ctx, name, version, snap)
+		if err != nil || ok {
+			return snap, err
+		}
+	}
+}
+
+// CompareAndSwap implements breaker.SharedState.
+func (s *State) CompareAndSwap(ctx context.Context, name string, old int, snap breaker.Snapshot) (bool, error) {
+	for {
+		cur, version, err := s.get(ctx, name)
+		if err != nil || cur.Generation != old {
+			return false, err
+		}
+
+		ok, err := s.put(ctx, name, version, snap)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+}
+
+// get returns the snapshot of name and its version, 0 if there is none.
+func (s *State) get(ctx context.Context, name string) (breaker.Snapshot, int, error) {
+	out, err := s.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(s.Table),
+		Key:            map[string]types.AttributeValue{"name": &types.AttributeValueMemberS{Value: name}},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil || out.Item == nil {
+		return breaker.Snapshot{}, 0, err
+	}
+
+	data, ok := out.Item["snapshot"].(*types.AttributeValueMemberS)
+	if !ok {
+		return breaker.Snapshot{}, 0, errors.New("breakerdynamodb: item without snapshot")
+	}
+	n, ok := out.Item["version"].(*types.AttributeValueMemberN)
+	if !ok {
+		return breaker.Snapshot{}, 0, errors.New("breakerdynamodb: item without version")
+	}
+	version, err := strconv.Atoi(n.Value)
+	if err != nil {
+		return breaker.Snapshot{}, 0, err
+	}
+
+	var snap breaker.Snapshot
+	err = json.Unmarshal([]byte(data.Value), &snap)
+	return snap, version, err
+}
+
+// put writes snap as the item of name if it is still at version.
+func (s *State) put(ctx context.Context, name string, version int, snap breaker.Snapshot) (bool, error) {
+	snap.Counts.Buckets = nil
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return false, err
+	}
+
+	item := map[string]types.AttributeValue{
+		"name":     &types.AttributeValueMemberS{Value: name},
+		"snapshot": &types.AttributeValueMemberS{Value: string(data)},
+		"version":  &types.AttributeValueMemberN{Value: strconv.Itoa(version + 1)},
+	}
+	if s.TTL > 0 {
+		expires := time.Now().Add(s.TTL).Unix()
+		item["expires"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(expires, 10)}
+	}
+
+	input := &dynamodb.PutItemInput{
+		TableName: aws.String(s.Table),
+		Item:      item,
+	}
+	if version == 0 {
+		input.ConditionExpression = aws.String("attribute_not_exists(#name)")
+		input.ExpressionAttributeNames = map[string]string{"#name": "name"}
+	} else {
+		input.ConditionExpression = aws.String("version = :version")
+		input.ExpressionAttributeValues = map[string]types.AttributeValue{
+			":version": &types.AttributeValueMemberN{Value: strconv.Itoa(version)},
+		}
+	}
+
+	_, err = s.Client.PutItem(ctx, input)
+	var failed *types.ConditionalCheckFailedException
+	if errors.As(err, &failed) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,129 @@
+// Package breakerecho provides Echo middleware guarding routes with circuit breakers.
+package breakerecho
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+
+	"github.com/sj902/breaker"
+)
+
+// Config configures the middleware returned by MiddlewareWithConfig.
+type Config struct {
+	// Skipper skips the middleware for some requests, none are skipped if nil.
+	Skipper middleware.Skipper
+	// Breaker guards every route when set.
+	Breaker *breaker.CircuitBreaker
+	// Settings are used, when Breaker is nil, to create one circuit breaker per
+	// route, named after the route path.
+	Settings breaker.Settings
+	// RouteSettings override Settings for the given route paths.
+	RouteSettings map[string]breaker.Settings
+	// Reject writes the response for a rejected request, DefaultReject if nil.
+	Reject func(c echo.Context, cb *breaker.CircuitBreaker, err error) error
+
+	mutex    sync.Mutex
+	breakers map[string]*breaker.CircuitBreaker
+}
+
+// Rejection is the JSON body written by DefaultReject.
+type Rejection struct {
+	Error   string     `json:"error"`
+	Breaker string     `json:"breaker"`
+	State   string     `json:"state"`
+	RetryAt *time.Time `json:"retry_at,omitempty"`
+}
+
+// Middleware runs every request through cb with the default configuration.
+func Middleware(cb *breaker.CircuitBreaker) echo.MiddlewareFunc {
+	return MiddlewareWithConfig(&Config{Breaker: cb})
+}
+
+// MiddlewareWithConfig runs requests through the circuit breaker of their route.
+// Handler errors resolving to a 5xx status, 5xx responses and panics count as failures.
+func MiddlewareWithConfig(config *Config) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if config.Skipper != nil && config.Skipper(c) {
+				return next(c)
+			}
+
+			cb := config.breaker(c.Path())
+			done, err := cb.Allow()
+			if err != nil {
+				if config.Reject != nil {
+					return config.Reject(c, cb, err)
+				}
+				return DefaultReject(c, cb, err)
+			}
+
+			success := false
+			defer func() {
+				done(success)
+			}()
+
+			err = next(c)
+			success = status(c, err) < http.StatusInternalServerError
+			return err
+		}
+	}
+}
+
+// DefaultReject responds with 503, a Retry-After header and a Rejection body.
+func DefaultReject(c echo.Context, cb *breaker.CircuitBreaker, err error) error {
+	body := Rejection{
+		Error:   err.Error(),
+		Breaker: cb.Name(),
+		State:   cb.State().String(),
+	}
+	if d := cb.RetryAfter(); d > 0 {
+		retryAt := time.Now().Add(d)
+		body.RetryAt = &retryAt
+		c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(d.Seconds()))))
+	}
+	return c.JSON(http.StatusServiceUnavailable, body)
+}
+
+func (config *Config) breaker(path string) *breaker.CircuitBreaker {
+	if config.Breaker != nil {
+		return config.Breaker
+	}
+
+	config.mutex.Lock()
+	defer config.mutex.Unlock()
+
+	if config.breakers == nil {
+		config.breakers = make(map[string]*breaker.CircuitBreaker)
+	}
+
+	cb, ok := config.breakers[path]
+	if !ok {
+		st, ok := config.RouteSettings[path]
+		if !ok {
+			st = config.Settings
+		}
+		st.Name = path
+		cb = breaker.NewCircuitBreaker(st)
+		config.breakers[path] = cb
+	}
+	return cb
+}
+
+// status returns the status the response has, or will have once err is handled.
+func status(c echo.Context, err error) int {
+	if err == nil {
+		return c.Response().Status
+	}
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		return he.Code
+	}
+	return http.StatusInternalServerError
+}
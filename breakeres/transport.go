@@ -0,0 +1,31 @@
+// Package breakeres guards the nodes of an Elasticsearch cluster with circuit breakers.
+package breakeres
+
+import (
+	"net/http"
+
+	"github.com/sj902/breaker"
+	"github.com/sj902/breaker/breakerhttp"
+)
+
+// IsFailureStatus classifies 5xx responses as failures. Unlike the breakerhttp
+// default, 429 is left to the client, which already retries it with backoff.
+func IsFailureStatus(status int) bool {
+	return status >= http.StatusInternalServerError
+}
+
+// NewTransport returns an http.RoundTripper for the Transport field of the
+// go-elasticsearch configuration, keeping a circuit breaker per node. Transport
+// errors, including timeouts, and 5xx responses count as failures.
+//
+// The client performs every attempt against one node through this RoundTripper,
+// so a rejection by an open breaker is handled like a connection error: the
+// node is marked dead and the request is retried on the next one.
+func NewTransport(base http.RoundTripper, st breaker.Settings) *breakerhttp.Transport {
+	return &breakerhttp.Transport{
+		Base:      base,
+		Settings:  st,
+		Key:       breakerhttp.KeyByHost,
+		IsFailure: IsFailureStatus,
+	}
+}
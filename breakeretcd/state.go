@@ -0,0 +1,181 @@
+// Package breakeretcd provides an etcd backend for the distributed mode of circuit breakers.
+package breakeretcd
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/sj902/breaker"
+)
+
+// State is a breaker.SharedState keeping one JSON snapshot per circuit
+// breaker in etcd, updated by transactions on the revision of the key:
+//
+//	st.SharedState = &breakeretcd.State{Client: cli, Prefix: "/myservice/breakers/", TTL: time.Hour}
+//
+// Breakers sync every Settings.SyncInterval; Watch propagates the changes
+// of other instances at once. A partitioned instance fails to sync and keeps
+// working on its own counts until it can reach etcd again.
+type State struct {
+	Client *clientv3.Client
+	// Prefix is prepended to the breaker name to form the key.
+	Prefix string
+	// TTL attaches keys to a lease kept alive by the last instance writing
+	// them, so that the keys of breakers no longer used expire. Zero keeps
+	// them forever.
+	TTL time.Duration
+
+	mutex sync.Mutex
+	lease clientv3.LeaseID
+}
+
+var _ breaker.SharedState = &State{}
+
+// Load implements breaker.SharedState.
+func (s *State) Load(ctx context.Context, name string) (breaker.Snapshot, bool, error) {
+	snap, rev, err := s.get(ctx, s.Prefix+name)
+	return snap, rev != 0, err
+}
+
+// Add implements breaker.SharedState.
+func (s *State) Add(ctx context.Context, name string, generation int, delta breaker.Counts) (breaker.Snapshot, error) {
+	key := s.Prefix + name
+	for {
+		snap, rev, err := s.get(ctx, key)
+		if err != nil {
+			return breaker.Snapshot{}, err
+		}
+		if rev == 0 {
+			snap.Name = name
+			snap.State = breaker.StateClosed
+		}
+		if snap.Generation != generation {
+			return snap, nil
+		}
+
+		snap.Counts.Merge(delta)
+		ok, err := s.put(ctx, key, rev, snap)
+		if err != nil || ok {
+			return snap, err
+		}
+	}
+}
+
+// CompareAndSwap implements breaker.SharedState.
+func (s *State) CompareAndSwap(ctx context.Context, name string, old int, snap breaker.Snapshot) (bool, error) {
+	key := s.Prefix + name
+	for {
+		cur, rev, err := s.get(ctx, key)
+		if err != nil || cur.Generation != old {
+			return false, err
+		}
+
+		ok, err := s.put(ctx, key, rev, snap)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+}
+
+// Watch calls Sync on the circuit breakers whose key changes until ctx is
+// done, so that they adopt the state of other instances within the time of
+// a round trip to etcd.
+func (s *State) Watch(ctx context.Context, cbs ...*breaker.CircuitBreaker) {
+	byName := make(map[string]*breaker.CircuitBreaker, len(cbs))
+	for _, cb := range cbs {
+		byName[cb.Name()] = cb
+	}
+
+	ctx = clientv3.WithRequireLeader(ctx)
+	for ctx.Err() == nil {
+		for resp := range s.Client.Watch(ctx, s.Prefix, clientv3.WithPrefix()) {
+			for _, ev := range resp.Events {
+				if cb, ok := byName[strings.TrimPrefix(string(ev.Kv.Key), s.Prefix)]; ok {
+					cb.Sync()
+				}
+			}
+		}
+
+		// The watch was canceled, e.g. on losing the leader: watch again.
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// get returns the snapshot at key and its revision, 0 if there is none.
+func (s *State) get(ctx context.Context, key string) (breaker.Snapshot, int64, error) {
+	resp, err := s.Client.Get(ctx, key)
+	if err != nil || len(resp.Kvs) == 0 {
+		return breaker.Snapshot{}, 0, err
+	}
+
+	var snap breaker.Snapshot
+	err = json.Unmarshal(resp.Kvs[0].Value, &snap)
+	return snap, resp.Kvs[0].ModRevision, err
+}
+
+// put writes snap to key if it is still at revision rev.
+func (s *State) put(ctx context.Context, key string, rev int64, snap breaker.Snapshot) (bool, error) {
+	snap.Counts.Buckets = nil
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return false, err
+	}
+
+	var opts []clientv3.OpOption
+	if s.TTL > 0 {
+		lease, err := s.grant(ctx)
+		if err != nil {
+			return false, err
+		}
+		opts = append(opts, clientv3.WithLease(lease))
+	}
+
+	resp, err := s.Client.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(key), "=", rev)).
+		Then(clientv3.OpPut(key, string(data), opts...)).
+		Commit()
+	if err != nil {
+		return false, err
+	}
+	return resp.Succeeded, nil
+}
+
+// grant returns the lease of the instance, granting a new one when the last
+// one could not be kept alive, e.g. after a partition longer than TTL.
+func (s *State) grant(ctx context.Context) (clientv3.LeaseID, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.lease != 0 {
+		return s.lease, nil
+	}
+
+	resp, err := s.Client.Grant(ctx, int64(s.TTL.Seconds()))
+	if err != nil {
+		return 0, err
+	}
+	ch, err := s.Client.KeepAlive(context.Background(), resp.ID)
+	if err != nil {
+		return 0, err
+	}
+	go func() {
+		for range ch {
+		}
+		s.mutex.Lock()
+		if s.lease == resp.ID {
+			s.lease = 0
+		}
+		s.mutex.Unlock()
+	}()
+
+	s.lease = resp.ID
+	return s.lease, nil
+}
@@ -0,0 +1,46 @@
+// Package breakerfiber provides Fiber middleware guarding routes with circuit breakers.
+package breakerfiber
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/sj902/breaker"
+	"github.com/sj902/breaker/breakerfasthttp"
+)
+
+// New returns a Fiber handler running the requests of the routes it is mounted
+// on through cb. Handler errors resolving to a 5xx status, 5xx responses and
+// panics count as failures. While cb rejects requests it responds with 503.
+func New(cb *breaker.CircuitBreaker) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		done, err := cb.Allow()
+		if err != nil {
+			breakerfasthttp.Reject(c.Context(), cb)
+			return nil
+		}
+
+		success := false
+		defer func() {
+			done(success)
+		}()
+
+		err = c.Next()
+		success = status(c, err) < fiber.StatusInternalServerError
+		return err
+	}
+}
+
+// status returns the status the response has, or will have once err is handled.
+func status(c *fiber.Ctx, err error) int {
+	if err == nil {
+		return c.Response().StatusCode()
+	}
+
+	var fe *fiber.Error
+	if errors.As(err, &fe) {
+		return fe.Code
+	}
+	return fiber.StatusInternalServerError
+}
@@ -0,0 +1,40 @@
+// Package breakerfasthttp guards fasthttp request handlers with circuit breakers.
+package breakerfasthttp
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/sj902/breaker"
+)
+
+// Middleware runs every request handled by next through cb. Responses with a
+// 5xx status and panics count as failures. While cb rejects requests, it
+// responds with 503 and a Retry-After header instead of calling next.
+func Middleware(cb *breaker.CircuitBreaker, next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		done, err := cb.Allow()
+		if err != nil {
+			Reject(ctx, cb)
+			return
+		}
+
+		success := false
+		defer func() {
+			done(success)
+		}()
+
+		next(ctx)
+		success = ctx.Response.StatusCode() < fasthttp.StatusInternalServerError
+	}
+}
+
+// Reject writes the 503 response with a Retry-After header for a request rejected by cb.
+func Reject(ctx *fasthttp.RequestCtx, cb *breaker.CircuitBreaker) {
+	if d := cb.RetryAfter(); d > 0 {
+		ctx.Response.Header.Set("Retry-After", strconv.Itoa(int(math.Ceil(d.Seconds()))))
+	}
+	ctx.Error(fasthttp.StatusMessage(fasthttp.StatusServiceUnavailable), fasthttp.StatusServiceUnavailable)
+}
@@ -0,0 +1,56 @@
+// Package breakergin provides gin middleware guarding routes with circuit breakers.
+package breakergin
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sj902/breaker"
+)
+
+// Rejection is the JSON body written while the circuit breaker rejects requests.
+type Rejection struct {
+	Error   string     `json:"error"`
+	Breaker string     `json:"breaker"`
+	State   string     `json:"state"`
+	RetryAt *time.Time `json:"retry_at,omitempty"`
+}
+
+// Middleware runs the requests of a route or route group through cb. Responses
+// with a 5xx status and panics count as failures. While cb rejects requests it
+// aborts with 503, a Retry-After header and a Rejection body.
+func Middleware(cb *breaker.CircuitBreaker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		done, err := cb.Allow()
+		if err != nil {
+			reject(c, cb, err)
+			return
+		}
+
+		success := false
+		defer func() {
+			done(success)
+		}()
+
+		c.Next()
+		success = c.Writer.Status() < http.StatusInternalServerError
+	}
+}
+
+func reject(c *gin.Context, cb *breaker.CircuitBreaker, err error) {
+	body := Rejection{
+		Error:   err.Error(),
+		Breaker: cb.Name(),
+		State:   cb.State().String(),
+	}
+	if d := cb.RetryAfter(); d > 0 {
+		retryAt := time.Now().Add(d)
+		body.RetryAt = &retryAt
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(d.Seconds()))))
+	}
+	c.AbortWithStatusJSON(http.StatusServiceUnavailable, body)
+}
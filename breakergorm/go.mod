@@ -0,0 +1,16 @@
+module github.com/sj902/breaker/breakergorm
+
+go 1.21
+
+require (
+	github.com/sj902/breaker v0.0.0
+	gorm.io/gorm v1.25.12
+)
+
+require (
+	github.com/jinzhu/inflection v1.0.0 // indirect
+	github.com/jinzhu/now v1.1.5 // indirect
+	golang.org/x/text v0.14.0 // indirect
+)
+
+replace github.com/sj902/breaker => ../
@@ -0,0 +1,126 @@
+// Package breakergorm provides a GORM plugin running statements through circuit breakers.
+package breakergorm
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"gorm.io/gorm"
+
+	"github.com/sj902/breaker"
+)
+
+// Plugin is a GORM plugin running create, query, update, delete, row and raw
+// statements through the circuit breaker of their key. Statements are rejected
+// with the breaker error before reaching the database while it is open.
+type Plugin struct {
+	// Settings are used to create the circuit breaker of every key, named after the key.
+	Settings breaker.Settings
+	// Key returns the key of the circuit breaker for a statement. If nil, all
+	// statements of the connection share one circuit breaker named after Settings.Name.
+	Key func(db *gorm.DB) string
+	// IsFailure reports whether a statement error counts as a failure. IsFailure if nil.
+	IsFailure func(error) bool
+
+	mutex    sync.Mutex
+	breakers map[string]*breaker.CircuitBreaker
+}
+
+const doneKey = "breaker:done"
+
+// IsFailure reports whether err counts as a failure, that is any error other
+// than gorm.ErrRecordNotFound and context cancellation.
+func IsFailure(err error) bool {
+	return err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && !errors.Is(err, context.Canceled)
+}
+
+// KeyByTable keys circuit breakers by the table of the statement.
+func KeyByTable(db *gorm.DB) string {
+	return db.Statement.Table
+}
+
+func (p *Plugin) Name() string {
+	return "breaker"
+}
+
+func (p *Plugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	errs := []error{
+		cb.Create().Before("gorm:create").Register("breaker:before_create", p.before),
+		cb.Create().After("gorm:create").Register("breaker:after_create", p.after),
+		cb.Query().Before("gorm:query").Register("breaker:before_query", p.before),
+		cb.Query().After("gorm:query").Register("breaker:after_query", p.after),
+		cb.Update().Before("gorm:update").Register("breaker:before_update", p.before),
+		cb.Update().After("gorm:update").Register("breaker:after_update", p.after),
+		cb.Delete().Before("gorm:delete").Register("breaker:before_delete", p.before),
+		cb.Delete().After("gorm:delete").Register("breaker:after_delete", p.after),
+		cb.Row().Before("gorm:row").Register("breaker:before_row", p.before),
+		cb.Row().After("gorm:row").Register("breaker:after_row", p.after),
+		cb.Raw().Before("gorm:raw").Register("breaker:before_raw", p.before),
+		cb.Raw().After("gorm:raw").Register("breaker:after_raw", p.after),
+	}
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Breaker returns the circuit breaker of key, creating it on first use.
+func (p *Plugin) Breaker(key string) *breaker.CircuitBreaker {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.breakers == nil {
+		p.breakers = make(map[string]*breaker.CircuitBreaker)
+	}
+
+	cb, ok := p.breakers[key]
+	if !ok {
+		st := p.Settings
+		if p.Key != nil {
+			st.Name = key
+		}
+		cb = breaker.NewCircuitBreaker(st)
+		p.breakers[key] = cb
+	}
+	return cb
+}
+
+func (p *Plugin) before(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+
+	var key string
+	if p.Key != nil {
+		key = p.Key(db)
+	}
+
+	done, err := p.Breaker(key).Allow()
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+	db.InstanceSet(doneKey, done)
+}
+
+func (p *Plugin) after(db *gorm.DB) {
+	v, ok := db.InstanceGet(doneKey)
+	if !ok {
+		return
+	}
+	done, ok := v.(func(bool))
+	if !ok {
+		return
+	}
+
+	isFailure := p.IsFailure
+	if isFailure == nil {
+		isFailure = IsFailure
+	}
+	done(!isFailure(db.Error))
+}
@@ -0,0 +1,206 @@
+// Package breakergossip shares circuit breaker trips between instances over
+// memberlist gossip, without a central store.
+package breakergossip
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/memberlist"
+
+	"github.com/sj902/breaker"
+)
+
+// Cluster broadcasts the trips and recoveries of the watched circuit breakers
+// to its peers. When a peer trips, the breaker of the same name trips too, so
+// that the dependency is spared before every instance has seen it fail. When
+// that peer recovers, breakers it opened are closed again, unless an override
+// pins them. Breakers that opened on their own counts recover on their own
+// probes.
+type Cluster struct {
+	list  *memberlist.Memberlist
+	queue *memberlist.TransmitLimitedQueue
+
+	mutex    sync.Mutex
+	breakers map[string]*breaker.CircuitBreaker
+	// remote holds the breakers opened by a peer.
+	remote map[string]bool
+}
+
+// message is the gossip message sent on a trip or recovery.
+type message struct {
+	Node  string        `json:"node"`
+	Name  string        `json:"name"`
+	State breaker.State `json:"state"`
+}
+
+// New creates the memberlist of the cluster from conf, e.g.
+// memberlist.DefaultLANConfig(). Call Join to join the other instances.
+func New(conf *memberlist.Config) (*Cluster, error) {
+	c := &Cluster{
+		breakers: make(map[string]*breaker.CircuitBreaker),
+		remote:   make(map[string]bool),
+	}
+
+	conf.Delegate = delegate{c}
+	list, err := memberlist.Create(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	c.list = list
+	c.queue = &memberlist.TransmitLimitedQueue{
+		NumNodes:       list.NumMembers,
+		RetransmitMult: conf.RetransmitMult,
+	}
+	return c, nil
+}
+
+// Join joins the cluster through any of the given existing members and
+// returns the number of members contacted.
+func (c *Cluster) Join(existing []string) (int, error) {
+	return c.list.Join(existing)
+}
+
+// Leave broadcasts that the instance leaves the cluster, waiting up to
+// timeout, and shuts it down.
+func (c *Cluster) Leave(timeout time.Duration) error {
+	if err := c.list.Leave(timeout); err != nil {
+		return err
+	}
+	return c.list.Shutdown()
+}
+
+// Members returns the members of the cluster.
+func (c *Cluster) Members() []*memberlist.Node {
+	return c.list.Members()
+}
+
+// Watch broadcasts the trips and recoveries of cb and applies those of its
+// peers to it until ctx is done.
+func (c *Cluster) Watch(ctx context.Context, cb *breaker.CircuitBreaker) {
+	name := cb.Name()
+	events := cb.EventsContext(ctx)
+
+	c.mutex.Lock()
+	c.breakers[name] = cb
+	c.mutex.Unlock()
+
+	defer func() {
+		c.mutex.Lock()
+		delete(c.breakers, name)
+		delete(c.remote, name)
+		c.mutex.Unlock()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			if ev.Type != breaker.EventTransition || ev.To == breaker.StateHalfOpen {
+				continue
+			}
+
+			c.mutex.Lock()
+			remote := c.remote[name]
+			if ev.To == breaker.StateClosed {
+				delete(c.remote, name)
+			}
+			c.mutex.Unlock()
+
+			if !remote {
+				c.broadcast(name, ev.To)
+			}
+		}
+	}
+}
+
+// broadcast queues a message for the peers. It replaces any message about
+// the same breaker not sent yet.
+func (c *Cluster) broadcast(name string, state breaker.State) {
+	msg, err := json.Marshal(message{Node: c.list.LocalNode().Name, Name: name, State: state})
+	if err != nil {
+		return
+	}
+	c.queue.QueueBroadcast(&broadcast{name: name, msg: msg})
+}
+
+// receive applies the message of a peer.
+func (c *Cluster) receive(m message) {
+	c.mutex.Lock()
+	cb, ok := c.breakers[m.Name]
+	if !ok {
+		c.mutex.Unlock()
+		return
+	}
+
+	switch m.State {
+	case breaker.StateOpen:
+		if cb.State() != breaker.StateOpen {
+			c.remote[m.Name] = true
+			c.mutex.Unlock()
+			cb.Trip()
+			return
+		}
+	case breaker.StateClosed:
+		// Recover, unlike Reset, leaves operator overrides in place.
+		if c.remote[m.Name] && cb.State() != breaker.StateClosed {
+			c.mutex.Unlock()
+			cb.Recover()
+			return
+		}
+	}
+	c.mutex.Unlock()
+}
+
+// delegate implements memberlist.Delegate.
+type delegate struct {
+	c *Cluster
+}
+
+func (d delegate) NodeMeta(limit int) []byte {
+	return nil
+}
+
+func (d delegate) NotifyMsg(data []byte) {
+	var m message
+	if err := json.Unmarshal(data, &m); err != nil {
+		return
+	}
+	d.c.receive(m)
+}
+
+func (d delegate) GetBroadcasts(overhead, limit int) [][]byte {
+	return d.c.queue.GetBroadcasts(overhead, limit)
+}
+
+func (d delegate) LocalState(join bool) []byte {
+	return nil
+}
+
+func (d delegate) MergeRemoteState(buf []byte, join bool) {
+}
+
+// broadcast implements memberlist.Broadcast.
+type broadcast struct {
+	name string
+	msg  []byte
+}
+
+func (b *broadcast) Invalidates(other memberlist.Broadcast) bool {
+	o, ok := other.(*broadcast)
+	return ok && o.name == b.name
+}
+
+func (b *broadcast) Message() []byte {
+	return b.msg
+}
+
+func (b *broadcast) Finished() {
+}
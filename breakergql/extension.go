@@ -0,0 +1,93 @@
+// Package breakergql provides a gqlgen extension running resolvers through circuit breakers.
+package breakergql
+
+import (
+	"context"
+	"sync"
+
+	"github.com/99designs/gqlgen/graphql"
+
+	"github.com/sj902/breaker"
+)
+
+// Extension is a gqlgen handler extension running resolver fields through the
+// circuit breaker of their key. A rejected or failed field resolves to null with
+// an error, so the rest of the query still returns a partial result.
+type Extension struct {
+	// Settings are used to create the circuit breaker of every key, named after the key.
+	Settings breaker.Settings
+	// Key returns the key of the circuit breaker for the field being resolved,
+	// or "" to leave it unguarded. FieldKey if nil.
+	Key func(ctx context.Context) string
+
+	mutex    sync.Mutex
+	breakers map[string]*breaker.CircuitBreaker
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.FieldInterceptor
+} = &Extension{}
+
+// FieldKey keys circuit breakers by resolver field, e.g. "Query.user".
+// Fields without a resolver are left unguarded.
+func FieldKey(ctx context.Context) string {
+	fc := graphql.GetFieldContext(ctx)
+	if fc == nil || !fc.IsResolver {
+		return ""
+	}
+	return fc.Object + "." + fc.Field.Name
+}
+
+// OperationKey keys circuit breakers by operation name, so that all resolver
+// fields of an operation share one circuit breaker.
+func OperationKey(ctx context.Context) string {
+	fc := graphql.GetFieldContext(ctx)
+	if fc == nil || !fc.IsResolver || !graphql.HasOperationContext(ctx) {
+		return ""
+	}
+	return graphql.GetOperationContext(ctx).OperationName
+}
+
+func (e *Extension) ExtensionName() string {
+	return "CircuitBreaker"
+}
+
+func (e *Extension) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (e *Extension) InterceptField(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+	key := e.key(ctx)
+	if key == "" {
+		return next(ctx)
+	}
+
+	return e.Breaker(key).ExecuteContext(ctx, next)
+}
+
+// Breaker returns the circuit breaker of key, creating it on first use.
+func (e *Extension) Breaker(key string) *breaker.CircuitBreaker {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.breakers == nil {
+		e.breakers = make(map[string]*breaker.CircuitBreaker)
+	}
+
+	cb, ok := e.breakers[key]
+	if !ok {
+		st := e.Settings
+		st.Name = key
+		cb = breaker.NewCircuitBreaker(st)
+		e.breakers[key] = cb
+	}
+	return cb
+}
+
+func (e *Extension) key(ctx context.Context) string {
+	if e.Key == nil {
+		return FieldKey(ctx)
+	}
+	return e.Key(ctx)
+}
@@ -0,0 +1,16 @@
+module github.com/sj902/breaker/breakergql
+
+go 1.21
+
+require (
+	github.com/99designs/gqlgen v0.17.49
+	github.com/sj902/breaker v0.0.0
+)
+
+require (
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/sosodev/duration v1.3.1 // indirect
+	github.com/vektah/gqlparser/v2 v2.5.16 // indirect
+)
+
+replace github.com/sj902/breaker => ../
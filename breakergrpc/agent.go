@@ -0,0 +1,152 @@
+package breakergrpc
+
+import (
+	"context"
+	"encoding/json"
+
+	"google.golang.org/grpc"
+
+	"github.com/sj902/breaker"
+)
+
+// The agent protocol lets breakers share their state through a local agent,
+// e.g. a sidecar aggregating the outcomes of all pods, instead of accessing a
+// datastore themselves. Its messages are JSON encoded, so that any gRPC
+// implementation can speak it without generated code.
+const agentService = "breaker.v1.Agent"
+
+type loadRequest struct {
+	Name string `json:"name"`
+}
+
+type loadResponse struct {
+	Snapshot breaker.Snapshot `json:"snapshot"`
+	Found    bool             `json:"found"`
+}
+
+type compareAndSwapRequest struct {
+	Name     string           `json:"name"`
+	Old      int              `json:"old"`
+	Snapshot breaker.Snapshot `json:"snapshot"`
+}
+
+type compareAndSwapResponse struct {
+	Swapped bool `json:"swapped"`
+}
+
+type addRequest struct {
+	Name       string         `json:"name"`
+	Generation int            `json:"generation"`
+	Delta      breaker.Counts `json:"delta"`
+}
+
+// jsonCodec is the codec of the agent protocol. It is not registered, so that
+// importing the package leaves the codecs of the program alone; AgentState
+// forces it on its calls and AgentServerOption on the agent server.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) Name() string {
+	return "breaker-agent-json"
+}
+
+// AgentState is a breaker.SharedState delegating to an agent served by
+// RegisterAgent, typically on localhost:
+//
+//	conn, err := grpc.NewClient("localhost:7070", grpc.WithTransportCredentials(insecure.NewCredentials()))
+//	st.SharedState = &breakergrpc.AgentState{Conn: conn}
+type AgentState struct {
+	Conn grpc.ClientConnInterface
+}
+
+var _ breaker.SharedState = &AgentState{}
+
+// Load implements breaker.SharedState.
+func (a *AgentState) Load(ctx context.Context, name string) (breaker.Snapshot, bool, error) {
+	var resp loadResponse
+	err := a.invoke(ctx, "Load", &loadRequest{Name: name}, &resp)
+	return resp.Snapshot, resp.Found, err
+}
+
+// CompareAndSwap implements breaker.SharedState.
+func (a *AgentState) CompareAndSwap(ctx context.Context, name string, old int, s breaker.Snapshot) (bool, error) {
+	s.Counts.Buckets = nil
+	var resp compareAndSwapResponse
+	err := a.invoke(ctx, "CompareAndSwap", &compareAndSwapRequest{Name: name, Old: old, Snapshot: s}, &resp)
+	return resp.Swapped, err
+}
+
+// Add implements breaker.SharedState.
+func (a *AgentState) Add(ctx context.Context, name string, generation int, delta breaker.Counts) (breaker.Snapshot, error) {
+	delta.Buckets = nil
+	var resp breaker.Snapshot
+	err := a.invoke(ctx, "Add", &addRequest{Name: name, Generation: generation, Delta: delta}, &resp)
+	return resp, err
+}
+
+func (a *AgentState) invoke(ctx context.Context, method string, req, resp interface{}) error {
+	return a.Conn.Invoke(ctx, "/"+agentService+"/"+method, req, resp, grpc.ForceCodec(jsonCodec{}))
+}
+
+// AgentServerOption returns the option a server serving the agent must be
+// created with. It makes the server use the JSON codec of the agent protocol
+// for all its services, so the agent needs a server of its own:
+//
+//	s := grpc.NewServer(breakergrpc.AgentServerOption())
+//	breakergrpc.RegisterAgent(s, &breaker.MemoryState{})
+func AgentServerOption() grpc.ServerOption {
+	return grpc.ForceServerCodec(jsonCodec{})
+}
+
+// RegisterAgent registers the agent service on s, serving the shared state
+// st, e.g. a breaker.MemoryState or a datastore backend, to AgentState clients.
+// s must be created with AgentServerOption.
+func RegisterAgent(s grpc.ServiceRegistrar, st breaker.SharedState) {
+	s.RegisterService(&agentServiceDesc, st)
+}
+
+var agentServiceDesc = grpc.ServiceDesc{
+	ServiceName: agentService,
+	HandlerType: (*breaker.SharedState)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Load", Handler: agentHandler("Load", func(ctx context.Context, st breaker.SharedState, req *loadRequest) (interface{}, error) {
+			s, found, err := st.Load(ctx, req.Name)
+			return &loadResponse{Snapshot: s, Found: found}, err
+		})},
+		{MethodName: "CompareAndSwap", Handler: agentHandler("CompareAndSwap", func(ctx context.Context, st breaker.SharedState, req *compareAndSwapRequest) (interface{}, error) {
+			swapped, err := st.CompareAndSwap(ctx, req.Name, req.Old, req.Snapshot)
+			return &compareAndSwapResponse{Swapped: swapped}, err
+		})},
+		{MethodName: "Add", Handler: agentHandler("Add", func(ctx context.Context, st breaker.SharedState, req *addRequest) (interface{}, error) {
+			s, err := st.Add(ctx, req.Name, req.Generation, req.Delta)
+			return &s, err
+		})},
+	},
+}
+
+// agentHandler adapts f to a grpc.MethodDesc handler, running interceptors.
+func agentHandler[Req any](method string, f func(ctx context.Context, st breaker.SharedState, req *Req) (interface{}, error)) grpc.MethodHandler {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		req := new(Req)
+		if err := dec(req); err != nil {
+			return nil, err
+		}
+
+		st := srv.(breaker.SharedState)
+		if interceptor == nil {
+			return f(ctx, st, req)
+		}
+
+		info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + agentService + "/" + method}
+		return interceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return f(ctx, st, req.(*Req))
+		})
+	}
+}
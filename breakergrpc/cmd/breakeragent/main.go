@@ -0,0 +1,38 @@
+// Command breakeragent serves the shared state of circuit breakers to the
+// breakergrpc.AgentState of local services, e.g. as a sidecar. It keeps the
+// state in memory, so all services using one agent trip and recover together.
+//
+// Usage:
+//
+//	breakeragent [-addr localhost:7070]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+
+	"google.golang.org/grpc"
+
+	"github.com/sj902/breaker"
+	"github.com/sj902/breaker/breakergrpc"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:7070", "address to listen on")
+	flag.Parse()
+
+	lis, err := net.Listen("tcp", *addr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "breakeragent:", err)
+		os.Exit(1)
+	}
+
+	s := grpc.NewServer(breakergrpc.AgentServerOption())
+	breakergrpc.RegisterAgent(s, &breaker.MemoryState{})
+	if err := s.Serve(lis); err != nil {
+		fmt.Fprintln(os.Stderr, "breakeragent:", err)
+		os.Exit(1)
+	}
+}
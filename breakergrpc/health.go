@@ -0,0 +1,50 @@
+package breakergrpc
+
+import (
+	"context"
+	"time"
+
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/sj902/breaker"
+)
+
+// HealthSetter is implemented by *health.Server of google.golang.org/grpc/health.
+type HealthSetter interface {
+	SetServingStatus(service string, status healthpb.HealthCheckResponse_ServingStatus)
+}
+
+// HealthReporter publishes the state of critical circuit breakers through the
+// grpc.health.v1 service: Service is NOT_SERVING while any of them is open.
+type HealthReporter struct {
+	Server   HealthSetter
+	Service  string
+	Critical []*breaker.CircuitBreaker
+}
+
+// Update sets the serving status of Service from the current state of the critical breakers.
+func (h *HealthReporter) Update() {
+	status := healthpb.HealthCheckResponse_SERVING
+	for _, cb := range h.Critical {
+		if cb.State() == breaker.StateOpen {
+			status = healthpb.HealthCheckResponse_NOT_SERVING
+			break
+		}
+	}
+	h.Server.SetServingStatus(h.Service, status)
+}
+
+// Run calls Update every interval until ctx is done.
+func (h *HealthReporter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		h.Update()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Package breakergrpc runs gRPC calls through circuit breakers.
+package breakergrpc
+
+import (
+	"context"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/sj902/breaker"
+)
+
+// ErrorReason is the reason of the errdetails.ErrorInfo attached to rejections.
+const ErrorReason = "CIRCUIT_BREAKER_REJECTED"
+
+// IsFailureCode is the default classifier of call results: codes signalling an
+// unhealthy server count as failures, caller errors such as InvalidArgument or
+// NotFound count as successes.
+func IsFailureCode(code codes.Code) bool {
+	switch code {
+	case codes.Unknown, codes.DeadlineExceeded, codes.ResourceExhausted,
+		codes.Internal, codes.Unavailable, codes.DataLoss:
+		return true
+	default:
+		return false
+	}
+}
+
+// UnaryClientInterceptor returns an interceptor running every unary call through cb.
+// Rejections are returned as codes.Unavailable with RetryInfo and ErrorInfo details.
+func UnaryClientInterceptor(cb *breaker.CircuitBreaker) grpc.UnaryClientInterceptor {
+	return unaryClientInterceptor(single(cb))
+}
+
+func unaryClientInterceptor(breakerOf func(method string) *breaker.CircuitBreaker) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		cb := breakerOf(method)
+		done, err := cb.Allow()
+		if err != nil {
+			return rejection(cb, err)
+		}
+
+		err = invoker(ctx, method, req, reply, cc, opts...)
+		done(!IsFailureCode(status.Code(err)))
+
+		return err
+	}
+}
+
+// rejection converts a rejection of cb into a codes.Unavailable status error.
+func rejection(cb *breaker.CircuitBreaker, err error) error {
+	st := status.New(codes.Unavailable, err.Error())
+
+	details := []protoadapt.MessageV1{
+		&errdetails.ErrorInfo{
+			Reason:   ErrorReason,
+			Domain:   "github.com/sj902/breaker",
+			Metadata: map[string]string{"breaker": cb.Name(), "state": cb.State().String()},
+		},
+	}
+	if d := cb.RetryAfter(); d > 0 {
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(d)})
+	}
+
+	withDetails, detailErr := st.WithDetails(details...)
+	if detailErr != nil {
+		return st.Err()
+	}
+	return withDetails.Err()
+}
@@ -0,0 +1,73 @@
+package breakergrpc
+
+import (
+	"sync"
+
+	"google.golang.org/grpc"
+
+	"github.com/sj902/breaker"
+)
+
+// Keyed provides interceptors keeping one circuit breaker per key, so that a
+// failing method does not open the circuit for unrelated methods of the same connection.
+type Keyed struct {
+	// Settings are used to create the circuit breaker of every key, named after the key.
+	Settings breaker.Settings
+	// Key returns the key of the circuit breaker for a full method name, KeyByMethod if nil.
+	Key func(fullMethod string) string
+	// MaxBreakers bounds the number of circuit breakers kept, evicting the
+	// least recently used one beyond it. No limit if zero.
+	MaxBreakers int
+
+	once     sync.Once
+	registry *breaker.Registry
+}
+
+// KeyByMethod keys circuit breakers by the full method name, e.g. "/users.Users/GetUser".
+func KeyByMethod(fullMethod string) string {
+	return fullMethod
+}
+
+// Breaker returns the circuit breaker used for fullMethod, creating it on first use.
+func (k *Keyed) Breaker(fullMethod string) *breaker.CircuitBreaker {
+	key := KeyByMethod(fullMethod)
+	if k.Key != nil {
+		key = k.Key(fullMethod)
+	}
+
+	return k.Registry().Get(key)
+}
+
+// Registry returns the registry holding the circuit breakers of k.
+func (k *Keyed) Registry() *breaker.Registry {
+	k.once.Do(func() {
+		k.registry = breaker.NewBoundedRegistry(k.Settings, k.MaxBreakers)
+	})
+	return k.registry
+}
+
+// UnaryClientInterceptor is like the package level UnaryClientInterceptor with a circuit breaker per key.
+func (k *Keyed) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return unaryClientInterceptor(k.Breaker)
+}
+
+// StreamClientInterceptor is like the package level StreamClientInterceptor with a circuit breaker per key.
+func (k *Keyed) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return streamClientInterceptor(k.Breaker)
+}
+
+// UnaryServerInterceptor is like the package level UnaryServerInterceptor with a circuit breaker per key.
+func (k *Keyed) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return unaryServerInterceptor(k.Breaker)
+}
+
+// StreamServerInterceptor is like the package level StreamServerInterceptor with a circuit breaker per key.
+func (k *Keyed) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return streamServerInterceptor(k.Breaker)
+}
+
+func single(cb *breaker.CircuitBreaker) func(method string) *breaker.CircuitBreaker {
+	return func(string) *breaker.CircuitBreaker {
+		return cb
+	}
+}
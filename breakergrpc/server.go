@@ -0,0 +1,41 @@
+package breakergrpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	"github.com/sj902/breaker"
+)
+
+// UnaryServerInterceptor returns an interceptor rejecting inbound unary RPCs with
+// codes.Unavailable while cb is open. It only reads the state of cb, which is
+// expected to be driven by the calls to the dependency it protects.
+func UnaryServerInterceptor(cb *breaker.CircuitBreaker) grpc.UnaryServerInterceptor {
+	return unaryServerInterceptor(single(cb))
+}
+
+func unaryServerInterceptor(breakerOf func(method string) *breaker.CircuitBreaker) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		cb := breakerOf(info.FullMethod)
+		if cb.State() == breaker.StateOpen {
+			return nil, rejection(cb, breaker.ErrOpenState)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is the streaming counterpart of UnaryServerInterceptor.
+func StreamServerInterceptor(cb *breaker.CircuitBreaker) grpc.StreamServerInterceptor {
+	return streamServerInterceptor(single(cb))
+}
+
+func streamServerInterceptor(breakerOf func(method string) *breaker.CircuitBreaker) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		cb := breakerOf(info.FullMethod)
+		if cb.State() == breaker.StateOpen {
+			return rejection(cb, breaker.ErrOpenState)
+		}
+		return handler(srv, ss)
+	}
+}
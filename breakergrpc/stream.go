@@ -0,0 +1,77 @@
+package breakergrpc
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	"github.com/sj902/breaker"
+)
+
+// StreamClientInterceptor returns an interceptor running every stream through cb.
+// A stream is recorded once: when it cannot be established, when it ends with an
+// error or io.EOF, when the response of a stream without server streaming is
+// received, or when its context is done. Callers must receive until io.EOF or
+// cancel the context of a server stream they abandon, otherwise it holds its
+// slot in cb until the context is done.
+func StreamClientInterceptor(cb *breaker.CircuitBreaker) grpc.StreamClientInterceptor {
+	return streamClientInterceptor(single(cb))
+}
+
+func streamClientInterceptor(breakerOf func(method string) *breaker.CircuitBreaker) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		cb := breakerOf(method)
+		done, err := cb.Allow()
+		if err != nil {
+			return nil, rejection(cb, err)
+		}
+
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			done(!IsFailureCode(status.Code(err)))
+			return nil, err
+		}
+
+		s := &clientStream{ClientStream: cs, done: done, single: !desc.ServerStreams, finished: make(chan struct{})}
+		go s.watch(ctx)
+		return s, nil
+	}
+}
+
+// clientStream records the terminal result of the stream it wraps.
+type clientStream struct {
+	grpc.ClientStream
+	done     func(success bool)
+	single   bool
+	once     sync.Once
+	finished chan struct{}
+}
+
+func (s *clientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err == io.EOF || (err == nil && s.single) {
+		// A stream without server streaming ends with its single response.
+		s.finish(nil)
+	} else if err != nil {
+		s.finish(err)
+	}
+	return err
+}
+
+func (s *clientStream) finish(err error) {
+	s.once.Do(func() {
+		close(s.finished)
+		s.done(!IsFailureCode(status.Code(err)))
+	})
+}
+
+func (s *clientStream) watch(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+		s.finish(status.FromContextError(ctx.Err()).Err())
+	case <-s.finished:
+	}
+}
@@ -0,0 +1,118 @@
+package breakerhttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/sj902/breaker"
+)
+
+// Admin is an http.Handler exposing the circuit breakers of Registry to
+// operators. Mount it under a prefix with http.StripPrefix:
+//
+//	GET  /                 lists the breakers
+//	GET  /breaker?name=n   shows breaker n
+//	POST /open?name=n      forces breaker n open
+//	POST /close?name=n     forces breaker n closed
+//	POST /reset?name=n     removes any override and resets breaker n to closed
+type Admin struct {
+	Registry *breaker.Registry
+	// Authorize, if non-nil, is called for every request and rejects it with
+	// 403 Forbidden by returning an error. If nil, only GET requests are allowed.
+	Authorize func(r *http.Request) error
+}
+
+// BreakerStatus is the JSON representation of a circuit breaker returned by Admin.
+type BreakerStatus struct {
+	Name           string          `json:"name"`
+	State          breaker.State   `json:"state"`
+	Counts         breaker.Counts  `json:"counts"`
+	Metrics        breaker.Metrics `json:"metrics"`
+	RetryAfter     time.Duration   `json:"retryAfter"`
+	LastError      string          `json:"lastError,omitempty"`
+	TripError      string          `json:"tripError,omitempty"`
+	LastTransition time.Time       `json:"lastTransition"`
+}
+
+// Status returns the BreakerStatus of cb.
+func Status(cb *breaker.CircuitBreaker) BreakerStatus {
+	s := BreakerStatus{
+		Name:           cb.Name(),
+		State:          cb.State(),
+		Counts:         cb.Counts(),
+		Metrics:        cb.Metrics(),
+		RetryAfter:     cb.RetryAfter(),
+		LastTransition: cb.LastTransition(),
+	}
+	if err := cb.LastError(); err != nil {
+		s.LastError = err.Error()
+	}
+	if err := cb.TripError(); err != nil {
+		s.TripError = err.Error()
+	}
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (a *Admin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if a.Authorize != nil {
+		if err := a.Authorize(r); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+	} else if r.Method != http.MethodGet {
+		http.Error(w, "admin operations require an Authorize hook", http.StatusForbidden)
+		return
+	}
+
+	if r.URL.Path == "/" || r.URL.Path == "" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		cbs := a.Registry.Breakers()
+		statuses := make([]BreakerStatus, len(cbs))
+		for i, cb := range cbs {
+			statuses[i] = Status(cb)
+		}
+		writeJSON(w, statuses)
+		return
+	}
+
+	method := http.MethodPost
+	var op func(cb *breaker.CircuitBreaker)
+	switch r.URL.Path {
+	case "/breaker":
+		method = http.MethodGet
+	case "/open":
+		op = (*breaker.CircuitBreaker).ForceOpen
+	case "/close":
+		op = (*breaker.CircuitBreaker).ForceClosed
+	case "/reset":
+		op = (*breaker.CircuitBreaker).Reset
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != method {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	cb, ok := a.Registry.Lookup(r.URL.Query().Get("name"))
+	if !ok {
+		http.Error(w, "unknown circuit breaker", http.StatusNotFound)
+		return
+	}
+	if op != nil {
+		op(cb)
+	}
+	writeJSON(w, Status(cb))
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,55 @@
+package breakerhttp
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// IsFailureStatus is the default status classifier: 5xx and 429 responses are
+// failures, every other status, including the remaining 4xx, is a success.
+func IsFailureStatus(status int) bool {
+	return status >= http.StatusInternalServerError || status == http.StatusTooManyRequests
+}
+
+// FailureStatuses returns a status classifier treating exactly the given statuses as failures.
+func FailureStatuses(statuses ...int) func(status int) bool {
+	set := make(map[int]bool, len(statuses))
+	for _, s := range statuses {
+		set[s] = true
+	}
+	return func(status int) bool {
+		return set[status]
+	}
+}
+
+// StatusError is returned by CheckStatus for a response classified as a failure.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("http status %d", e.StatusCode)
+}
+
+// CheckStatus returns a *StatusError if isFailure, IsFailureStatus if nil,
+// classifies the status of resp as a failure. It lets hand-written Execute
+// closures report failed responses:
+//
+//	resp, err := breaker.Execute(cb, func() (*http.Response, error) {
+//		resp, err := client.Do(req)
+//		if err != nil {
+//			return nil, err
+//		}
+//		return resp, breakerhttp.CheckStatus(resp, nil)
+//	})
+//
+// The response is still returned with the error and its body must be closed.
+func CheckStatus(resp *http.Response, isFailure func(status int) bool) error {
+	if isFailure == nil {
+		isFailure = IsFailureStatus
+	}
+	if isFailure(resp.StatusCode) {
+		return &StatusError{StatusCode: resp.StatusCode}
+	}
+	return nil
+}
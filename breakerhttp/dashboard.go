@@ -0,0 +1,80 @@
+package breakerhttp
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sj902/breaker"
+)
+
+//go:embed dashboard.html
+var dashboardHTML []byte
+
+const defaultDashboardInterval = time.Second
+
+// Dashboard is an http.Handler serving a web dashboard of the circuit breakers
+// of Registry, with their live state, failure rate history and transitions.
+// The page is embedded in the binary and updated over Server-Sent Events.
+// Mount it under a prefix with http.StripPrefix.
+type Dashboard struct {
+	Registry *breaker.Registry
+	// Interval between two updates, 1s if zero.
+	Interval time.Duration
+}
+
+// ServeHTTP implements http.Handler.
+func (d *Dashboard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/", "":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(dashboardHTML)
+	case "/events":
+		d.events(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (d *Dashboard) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	interval := d.Interval
+	if interval <= 0 {
+		interval = defaultDashboardInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		cbs := d.Registry.Breakers()
+		statuses := make([]BreakerStatus, len(cbs))
+		for i, cb := range cbs {
+			statuses[i] = Status(cb)
+		}
+
+		data, err := json.Marshal(statuses)
+		if err != nil {
+			return
+		}
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package breakerhttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sj902/breaker"
+)
+
+const (
+	defaultHystrixInterval = 500 * time.Millisecond
+	defaultHystrixWindow   = 10 * time.Second
+)
+
+// HystrixStream is an http.Handler serving a hystrix.stream compatible
+// Server-Sent Events stream, so that Hystrix dashboards and Turbine can
+// display the circuit breakers. Rolling counts cover the last Window of the
+// connection; latencies are not tracked and are reported as zero.
+type HystrixStream struct {
+	// Interval between two events of a breaker, 500ms if zero.
+	Interval time.Duration
+	// Window of the rolling counts, 10s if zero.
+	Window time.Duration
+	// Registry, if non-nil, adds all its circuit breakers, including the ones
+	// created later, to the ones given to NewHystrixStream and Add.
+	Registry *breaker.Registry
+
+	mutex    sync.Mutex
+	breakers []*breaker.CircuitBreaker
+}
+
+// NewHystrixStream returns a HystrixStream for cbs.
+func NewHystrixStream(cbs ...*breaker.CircuitBreaker) *HystrixStream {
+	return &HystrixStream{breakers: cbs}
+}
+
+// Add adds cb to the circuit breakers streamed by s.
+func (s *HystrixStream) Add(cb *breaker.CircuitBreaker) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.breakers = append(s.breakers, cb)
+}
+
+type hystrixSample struct {
+	at      time.Time
+	metrics breaker.Metrics
+}
+
+// hystrixCommand holds the fields of a HystrixCommand event read by the dashboard.
+type hystrixCommand struct {
+	Type                               string         `json:"type"`
+	Name                               string         `json:"name"`
+	Group                              string         `json:"group"`
+	CurrentTime                        int64          `json:"currentTime"`
+	IsCircuitBreakerOpen               bool           `json:"isCircuitBreakerOpen"`
+	ErrorPercentage                    int            `json:"errorPercentage"`
+	ErrorCount                         uint64         `json:"errorCount"`
+	RequestCount                       uint64         `json:"requestCount"`
+	RollingCountFailure                uint64         `json:"rollingCountFailure"`
+	RollingCountSuccess                uint64         `json:"rollingCountSuccess"`
+	RollingCountShortCircuited         uint64         `json:"rollingCountShortCircuited"`
+	RollingCountTimeout                uint64         `json:"rollingCountTimeout"`
+	RollingCountThreadPoolRejected     uint64         `json:"rollingCountThreadPoolRejected"`
+	RollingCountSemaphoreRejected      uint64         `json:"rollingCountSemaphoreRejected"`
+	RollingCountFallbackSuccess        uint64         `json:"rollingCountFallbackSuccess"`
+	RollingCountFallbackFailure        uint64         `json:"rollingCountFallbackFailure"`
+	RollingCountFallbackRejection      uint64         `json:"rollingCountFallbackRejection"`
+	RollingCountExceptionsThrown       uint64         `json:"rollingCountExceptionsThrown"`
+	RollingCountResponsesFromCache     uint64         `json:"rollingCountResponsesFromCache"`
+	RollingCountCollapsedRequests      uint64         `json:"rollingCountCollapsedRequests"`
+	RollingCountBadRequests            uint64         `json:"rollingCountBadRequests"`
+	CurrentConcurrentExecutionCount    int            `json:"currentConcurrentExecutionCount"`
+	RollingMaxConcurrentExecutionCount int            `json:"rollingMaxConcurrentExecutionCount"`
+	LatencyExecuteMean                 int            `json:"latencyExecute_mean"`
+	LatencyExecute                     map[string]int `json:"latencyExecute"`
+	LatencyTotalMean                   int            `json:"latencyTotal_mean"`
+	LatencyTotal                       map[string]int `json:"latencyTotal"`
+	StatisticalWindow                  int64          `json:"propertyValue_metricsRollingStatisticalWindowInMilliseconds"`
+	IsolationStrategy                  string         `json:"propertyValue_executionIsolationStrategy"`
+	CircuitBreakerEnabled              bool           `json:"propertyValue_circuitBreakerEnabled"`
+	ReportingHosts                     int            `json:"reportingHosts"`
+}
+
+var hystrixPercentiles = map[string]int{
+	"0": 0, "25": 0, "50": 0, "75": 0, "90": 0, "95": 0, "99": 0, "99.5": 0, "100": 0,
+}
+
+// ServeHTTP implements http.Handler.
+func (s *HystrixStream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	interval, window := s.Interval, s.Window
+	if interval <= 0 {
+		interval = defaultHystrixInterval
+	}
+	if window <= 0 {
+		window = defaultHystrixWindow
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	history := make(map[*breaker.CircuitBreaker][]hystrixSample)
+	for {
+		s.mutex.Lock()
+		cbs := append([]*breaker.CircuitBreaker(nil), s.breakers...)
+		s.mutex.Unlock()
+		if s.Registry != nil {
+			cbs = append(cbs, s.Registry.Breakers()...)
+		}
+
+		now := time.Now()
+		current := make(map[*breaker.CircuitBreaker]bool, len(cbs))
+		for _, cb := range cbs {
+			if current[cb] {
+				continue
+			}
+			current[cb] = true
+
+			samples := append(history[cb], hystrixSample{at: now, metrics: cb.Metrics()})
+			for len(samples) > 1 && now.Sub(samples[0].at) > window {
+				samples = samples[1:]
+			}
+			history[cb] = samples
+
+			data, err := json.Marshal(hystrixEvent(cb, samples[0].metrics, samples[len(samples)-1].metrics, now, window))
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+				return
+			}
+		}
+		// Forget the breakers that left the registry since the last tick.
+		for cb := range history {
+			if !current[cb] {
+				delete(history, cb)
+			}
+		}
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func hystrixEvent(cb *breaker.CircuitBreaker, oldest, latest breaker.Metrics, now time.Time, window time.Duration) hystrixCommand {
+	success := latest.Successes - oldest.Successes
+	failure := latest.Failures - oldest.Failures
+	rejected := latest.Rejections - oldest.Rejections
+
+	total := success + failure + rejected
+	errors := failure + rejected
+	var percentage int
+	if total > 0 {
+		percentage = int(errors * 100 / total)
+	}
+
+	return hystrixCommand{
+		Type:                       "HystrixCommand",
+		Name:                       cb.Name(),
+		Group:                      cb.Name(),
+		CurrentTime:                now.UnixNano() / int64(time.Millisecond),
+		IsCircuitBreakerOpen:       cb.State() == breaker.StateOpen,
+		ErrorPercentage:            percentage,
+		ErrorCount:                 errors,
+		RequestCount:               total,
+		RollingCountFailure:        failure,
+		RollingCountSuccess:        success,
+		RollingCountShortCircuited: rejected,
+		LatencyExecute:             hystrixPercentiles,
+		LatencyTotal:               hystrixPercentiles,
+		StatisticalWindow:          window.Milliseconds(),
+		IsolationStrategy:          "SEMAPHORE",
+		CircuitBreakerEnabled:      true,
+		ReportingHosts:             1,
+	}
+}
@@ -0,0 +1,53 @@
+package breakerhttp
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/sj902/breaker"
+)
+
+// Keyed provides a middleware keeping one circuit breaker per key, e.g. per
+// route pattern, all created from the same default Settings.
+type Keyed struct {
+	// Settings are used to create the circuit breaker of every key, named after the key.
+	Settings breaker.Settings
+	// Key returns the key of the circuit breaker for req, KeyByPath if nil.
+	Key func(req *http.Request) string
+	// MaxBreakers bounds the number of circuit breakers kept, evicting the
+	// least recently used one beyond it. No limit if zero.
+	MaxBreakers int
+
+	once     sync.Once
+	registry *breaker.Registry
+}
+
+// KeyByPath keys circuit breakers by the path of the request URL.
+func KeyByPath(req *http.Request) string {
+	return req.URL.Path
+}
+
+// Breaker returns the circuit breaker used for req, creating it on first use.
+func (k *Keyed) Breaker(req *http.Request) *breaker.CircuitBreaker {
+	key := KeyByPath(req)
+	if k.Key != nil {
+		key = k.Key(req)
+	}
+
+	return k.Registry().Get(key)
+}
+
+// Registry returns the registry holding the circuit breakers of k.
+func (k *Keyed) Registry() *breaker.Registry {
+	k.once.Do(func() {
+		k.registry = breaker.NewBoundedRegistry(k.Settings, k.MaxBreakers)
+	})
+	return k.registry
+}
+
+// Middleware is like the package level Middleware with a circuit breaker per key.
+func (k *Keyed) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Middleware(k.Breaker(r), next).ServeHTTP(w, r)
+	})
+}
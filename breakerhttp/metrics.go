@@ -0,0 +1,133 @@
+package breakerhttp
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/sj902/breaker"
+)
+
+// MetricsHandler is an http.Handler rendering the state and lifetime metrics
+// of a set of circuit breakers in the Prometheus text format, or in the
+// OpenMetrics format when the scraper asks for it. The metrics are the same
+// as the ones of the breakerprom collector, without depending on the
+// Prometheus client library.
+type MetricsHandler struct {
+	// Registry, if non-nil, adds all its circuit breakers, including the ones
+	// created later, to the ones given to NewMetricsHandler and Add.
+	Registry *breaker.Registry
+
+	mutex    sync.Mutex
+	breakers []*breaker.CircuitBreaker
+}
+
+// NewMetricsHandler returns a MetricsHandler for cbs.
+func NewMetricsHandler(cbs ...*breaker.CircuitBreaker) *MetricsHandler {
+	return &MetricsHandler{breakers: cbs}
+}
+
+// Add adds cb to the circuit breakers rendered by h.
+func (h *MetricsHandler) Add(cb *breaker.CircuitBreaker) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.breakers = append(h.breakers, cb)
+}
+
+var metricStates = []breaker.State{breaker.StateClosed, breaker.StateHalfOpen, breaker.StateOpen}
+
+type counterFamily struct {
+	name  string
+	help  string
+	value func(m breaker.Metrics) uint64
+}
+
+var counterFamilies = []counterFamily{
+	{"circuit_breaker_requests", "Requests admitted by the circuit breaker.",
+		func(m breaker.Metrics) uint64 { return m.Requests }},
+	{"circuit_breaker_successes", "Requests that completed successfully.",
+		func(m breaker.Metrics) uint64 { return m.Successes }},
+	{"circuit_breaker_failures", "Requests that completed with a failure.",
+		func(m breaker.Metrics) uint64 { return m.Failures }},
+	{"circuit_breaker_rejections", "Requests rejected by the circuit breaker.",
+		func(m breaker.Metrics) uint64 { return m.Rejections }},
+}
+
+// ServeHTTP implements http.Handler.
+func (h *MetricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mutex.Lock()
+	cbs := append([]*breaker.CircuitBreaker(nil), h.breakers...)
+	h.mutex.Unlock()
+	if h.Registry != nil {
+		cbs = append(cbs, h.Registry.Breakers()...)
+	}
+
+	openMetrics := strings.Contains(r.Header.Get("Accept"), "application/openmetrics-text")
+	if openMetrics {
+		w.Header().Set("Content-Type", "application/openmetrics-text; version=1.0.0; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	}
+
+	states := make([]breaker.State, len(cbs))
+	metrics := make([]breaker.Metrics, len(cbs))
+	for i, cb := range cbs {
+		states[i] = cb.State()
+		metrics[i] = cb.Metrics()
+	}
+
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+
+	// header writes the metadata of a family; OpenMetrics names counter
+	// families without the _total suffix of their samples.
+	header := func(family, typ, help string) string {
+		sample := family
+		if typ == "counter" {
+			sample += "_total"
+			if !openMetrics {
+				family = sample
+			}
+		}
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n", family, help, family, typ)
+		return sample
+	}
+
+	name := header("circuit_breaker_state", "gauge", "Whether the circuit breaker is in the state, 1 or 0.")
+	for i, cb := range cbs {
+		for _, s := range metricStates {
+			v := 0
+			if s == states[i] {
+				v = 1
+			}
+			fmt.Fprintf(bw, "%s{name=\"%s\",state=\"%s\"} %d\n", name, escapeLabel(cb.Name()), s, v)
+		}
+	}
+
+	for _, f := range counterFamilies {
+		name := header(f.name, "counter", f.help)
+		for i, cb := range cbs {
+			fmt.Fprintf(bw, "%s{name=\"%s\"} %d\n", name, escapeLabel(cb.Name()), f.value(metrics[i]))
+		}
+	}
+
+	name = header("circuit_breaker_transitions", "counter", "Transitions of the circuit breaker into the state.")
+	for i, cb := range cbs {
+		for _, s := range metricStates {
+			fmt.Fprintf(bw, "%s{name=\"%s\",state=\"%s\"} %d\n", name, escapeLabel(cb.Name()), s, metrics[i].Transitions[s])
+		}
+	}
+
+	if openMetrics {
+		fmt.Fprint(bw, "# EOF\n")
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
@@ -0,0 +1,62 @@
+package breakerhttp
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/sj902/breaker"
+)
+
+// Middleware runs every request handled by next through cb. Responses with a
+// 5xx status and panics count as failures. While cb rejects requests, it
+// responds with 503 and a Retry-After header instead of calling next.
+func Middleware(cb *breaker.CircuitBreaker, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		done, err := cb.Allow()
+		if err != nil {
+			reject(w, cb)
+			return
+		}
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		success := false
+		defer func() {
+			done(success)
+		}()
+
+		next.ServeHTTP(rec, r)
+		success = rec.status < http.StatusInternalServerError
+	})
+}
+
+func reject(w http.ResponseWriter, cb *breaker.CircuitBreaker) {
+	if d := cb.RetryAfter(); d > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(d.Seconds()))))
+	}
+	http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+}
+
+// statusRecorder remembers the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,74 @@
+// Package breakerhttp runs net/http clients and servers through circuit breakers.
+package breakerhttp
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/sj902/breaker"
+)
+
+// Transport is an http.RoundTripper that runs every request through the circuit
+// breaker of its key. Transport errors and responses classified by IsFailure count as failures.
+type Transport struct {
+	// Base is the underlying RoundTripper, http.DefaultTransport if nil.
+	Base http.RoundTripper
+	// Settings are used to create the circuit breaker of every key, named after the key.
+	Settings breaker.Settings
+	// Key returns the key of the circuit breaker for req, KeyByHost if nil.
+	Key func(req *http.Request) string
+	// IsFailure reports whether a response status counts as a failure, IsFailureStatus if nil.
+	IsFailure func(status int) bool
+	// MaxBreakers bounds the number of circuit breakers kept, evicting the
+	// least recently used one beyond it. No limit if zero.
+	MaxBreakers int
+
+	once     sync.Once
+	registry *breaker.Registry
+}
+
+// KeyByHost keys circuit breakers by the host of the request URL, so that
+// failures of one host do not open the circuit for another.
+func KeyByHost(req *http.Request) string {
+	return req.URL.Host
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	done, err := t.Breaker(req).Allow()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base().RoundTrip(req)
+	done(err == nil && CheckStatus(resp, t.IsFailure) == nil)
+
+	return resp, err
+}
+
+// Breaker returns the circuit breaker used for req, creating it on first use.
+func (t *Transport) Breaker(req *http.Request) *breaker.CircuitBreaker {
+	return t.Registry().Get(t.key(req))
+}
+
+// Registry returns the registry holding the circuit breakers of t.
+func (t *Transport) Registry() *breaker.Registry {
+	t.once.Do(func() {
+		t.registry = breaker.NewBoundedRegistry(t.Settings, t.MaxBreakers)
+	})
+	return t.registry
+}
+
+func (t *Transport) key(req *http.Request) string {
+	if t.Key == nil {
+		return KeyByHost(req)
+	}
+	return t.Key(req)
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base == nil {
+		return http.DefaultTransport
+	}
+	return t.Base
+}
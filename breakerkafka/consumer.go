@@ -0,0 +1,76 @@
+package breakerkafka
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+
+	"github.com/sj902/breaker"
+)
+
+// Pausable is implemented by sarama.ConsumerGroup.
+type Pausable interface {
+	PauseAll()
+	ResumeAll()
+}
+
+// Pauser pauses consumption of Group while Breaker, which protects the
+// downstream processor, is open, and resumes it once the breaker lets probes
+// through again. This keeps a consumer from fetching and failing the same
+// messages over and over during an outage.
+type Pauser struct {
+	Group   Pausable
+	Breaker *breaker.CircuitBreaker
+
+	mutex  sync.Mutex
+	paused bool
+}
+
+// Update pauses or resumes Group from the current state of Breaker.
+func (p *Pauser) Update() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	open := p.Breaker.State() == breaker.StateOpen
+	if open == p.paused {
+		return
+	}
+
+	if open {
+		p.Group.PauseAll()
+	} else {
+		p.Group.ResumeAll()
+	}
+	p.paused = open
+}
+
+// Run calls Update every interval until ctx is done.
+func (p *Pauser) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		p.Update()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Process runs process for msg through cb and marks msg in sess only if it
+// succeeds, so that failed and rejected messages are not committed.
+func Process(sess sarama.ConsumerGroupSession, msg *sarama.ConsumerMessage, cb *breaker.CircuitBreaker, process func(*sarama.ConsumerMessage) error) error {
+	err := cb.Do(func() error {
+		return process(msg)
+	})
+	if err != nil {
+		return err
+	}
+
+	sess.MarkMessage(msg, "")
+	return nil
+}
@@ -0,0 +1,48 @@
+// Package breakerkafka runs Kafka producers and consumers through circuit breakers.
+package breakerkafka
+
+import (
+	"errors"
+
+	"github.com/IBM/sarama"
+
+	"github.com/sj902/breaker"
+)
+
+// SyncProducer is a sarama.SyncProducer sending messages through Breaker.
+type SyncProducer struct {
+	sarama.SyncProducer
+	Breaker *breaker.CircuitBreaker
+	// Spill, if non-nil, receives the messages rejected by Breaker along with the
+	// rejection, e.g. to buffer them for a later retry. Its result is returned
+	// in place of the rejection.
+	Spill func(msgs []*sarama.ProducerMessage, err error) error
+}
+
+// SendMessage implements sarama.SyncProducer.
+func (p *SyncProducer) SendMessage(msg *sarama.ProducerMessage) (partition int32, offset int64, err error) {
+	err = p.Breaker.Do(func() error {
+		partition, offset, err = p.SyncProducer.SendMessage(msg)
+		return err
+	})
+	if isRejection(err) && p.Spill != nil {
+		return -1, -1, p.Spill([]*sarama.ProducerMessage{msg}, err)
+	}
+	return partition, offset, err
+}
+
+// SendMessages implements sarama.SyncProducer.
+func (p *SyncProducer) SendMessages(msgs []*sarama.ProducerMessage) error {
+	err := p.Breaker.Do(func() error {
+		return p.SyncProducer.SendMessages(msgs)
+	})
+	if isRejection(err) && p.Spill != nil {
+		return p.Spill(msgs, err)
+	}
+	return err
+}
+
+func isRejection(err error) bool {
+	return errors.Is(err, breaker.ErrOpenState) || errors.Is(err, breaker.ErrTooManyRequests) ||
+		errors.Is(err, breaker.ErrBulkheadFull) || errors.Is(err, breaker.ErrQueueTimeout)
+}
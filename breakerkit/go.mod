@@ -0,0 +1,10 @@
+module github.com/sj902/breaker/breakerkit
+
+go 1.21
+
+require (
+	github.com/go-kit/kit v0.13.0
+	github.com/sj902/breaker v0.0.0
+)
+
+replace github.com/sj902/breaker => ../
@@ -0,0 +1,21 @@
+// Package breakerkit provides go-kit endpoint middleware running endpoints through circuit breakers.
+package breakerkit
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+
+	"github.com/sj902/breaker"
+)
+
+// Middleware returns an endpoint.Middleware running every call of the wrapped endpoint through cb.
+func Middleware(cb *breaker.CircuitBreaker) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			return cb.ExecuteContext(ctx, func(ctx context.Context) (interface{}, error) {
+				return next(ctx, request)
+			})
+		}
+	}
+}
@@ -0,0 +1,12 @@
+module github.com/sj902/breaker/breakerlogrus
+
+go 1.21
+
+require (
+	github.com/sirupsen/logrus v1.9.3
+	github.com/sj902/breaker v0.0.0
+)
+
+require golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
+
+replace github.com/sj902/breaker => ../
@@ -0,0 +1,35 @@
+// Package breakerlogrus adapts logrus loggers to breaker.Logger.
+package breakerlogrus
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/sj902/breaker"
+)
+
+type logger struct {
+	log logrus.FieldLogger
+}
+
+// Logger returns a breaker.Logger logging to l, with the key-value pairs as fields.
+func Logger(l logrus.FieldLogger) breaker.Logger {
+	return logger{log: l}
+}
+
+func (l logger) Info(msg string, args ...any) {
+	l.log.WithFields(fields(args)).Info(msg)
+}
+
+func (l logger) Warn(msg string, args ...any) {
+	l.log.WithFields(fields(args)).Warn(msg)
+}
+
+func fields(args []any) logrus.Fields {
+	f := make(logrus.Fields, len(args)/2)
+	for i := 0; i+1 < len(args); i += 2 {
+		f[fmt.Sprint(args[i])] = args[i+1]
+	}
+	return f
+}
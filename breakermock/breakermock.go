@@ -0,0 +1,189 @@
+// Package breakermock provides a fake circuit breaker to test code depending
+// on breaker.Breaker, e.g. its handling of open and half-open circuits,
+// without driving a real breaker through failures and timeouts.
+package breakermock
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sj902/breaker"
+)
+
+// Call records a request run through the fake.
+type Call struct {
+	// State is the state the request met.
+	State breaker.State
+	// Err is the error returned to the caller.
+	Err error
+	// Rejected reports whether the request was rejected without running.
+	Rejected bool
+}
+
+// Breaker is a fake breaker.Breaker. Its state only changes when set or
+// scripted, never because of outcomes. Requests are rejected with
+// breaker.ErrOpenState in the open state and run otherwise, unless an outcome
+// is scripted for them.
+type Breaker struct {
+	mutex    sync.Mutex
+	name     string
+	state    breaker.State
+	states   []breaker.State
+	outcomes []error
+	counts   breaker.Counts
+	calls    []Call
+}
+
+var _ breaker.Breaker = &Breaker{}
+
+// New returns a closed fake named name.
+func New(name string) *Breaker {
+	return &Breaker{name: name, state: breaker.StateClosed}
+}
+
+// SetState sets the state of the fake and drops the scripted states.
+func (b *Breaker) SetState(s breaker.State) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.state = s
+	b.states = nil
+}
+
+// ScriptStates sets the states met by the next requests, one per request.
+// The fake stays in the last one.
+func (b *Breaker) ScriptStates(states ...breaker.State) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.states = append(b.states[:0], states...)
+}
+
+// ScriptOutcomes sets the outcomes of the next admitted requests, one per
+// request: they return the error, nil for a success, without running. Later
+// requests run.
+func (b *Breaker) ScriptOutcomes(errs ...error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.outcomes = append(b.outcomes[:0], errs...)
+}
+
+// Calls returns the requests run through the fake.
+func (b *Breaker) Calls() []Call {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return append([]Call(nil), b.calls...)
+}
+
+// Name implements breaker.Breaker.
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+// State implements breaker.Breaker.
+func (b *Breaker) State() breaker.State {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.state
+}
+
+// Counts implements breaker.Breaker. It counts the admitted requests.
+func (b *Breaker) Counts() breaker.Counts {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.counts
+}
+
+// Execute implements breaker.Breaker.
+func (b *Breaker) Execute(req func() (interface{}, error)) (interface{}, error) {
+	state, scripted, outcome, err := b.before()
+	if err != nil {
+		return nil, err
+	}
+
+	var res interface{}
+	if scripted {
+		err = outcome
+	} else {
+		res, err = req()
+	}
+	b.after(state, err == nil, err)
+	return res, err
+}
+
+// ExecuteContext implements breaker.Breaker.
+func (b *Breaker) ExecuteContext(ctx context.Context, req func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return b.Execute(func() (interface{}, error) {
+		return req(ctx)
+	})
+}
+
+// Do implements breaker.Breaker.
+func (b *Breaker) Do(req func() error) error {
+	_, err := b.Execute(func() (interface{}, error) {
+		return nil, req()
+	})
+	return err
+}
+
+// Allow implements breaker.Breaker. A scripted outcome is ignored in favor of
+// the one passed to done, but still consumed.
+func (b *Breaker) Allow() (done func(success bool), err error) {
+	state, _, _, err := b.before()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(success bool) {
+		b.after(state, success, nil)
+	}, nil
+}
+
+// before advances the script and returns the state met by a request and its
+// scripted outcome, or the rejection error.
+func (b *Breaker) before() (state breaker.State, scripted bool, outcome error, err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if len(b.states) > 0 {
+		b.state = b.states[0]
+		b.states = b.states[1:]
+	}
+	state = b.state
+
+	if state == breaker.StateOpen {
+		b.calls = append(b.calls, Call{State: state, Err: breaker.ErrOpenState, Rejected: true})
+		return state, false, nil, breaker.ErrOpenState
+	}
+
+	if len(b.outcomes) > 0 {
+		scripted, outcome = true, b.outcomes[0]
+		b.outcomes = b.outcomes[1:]
+	}
+	return state, scripted, outcome, nil
+}
+
+func (b *Breaker) after(state breaker.State, success bool, err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.counts.Requests++
+	if success {
+		b.counts.TotalSuccess++
+		b.counts.ConsecutiveSuccess++
+		b.counts.ConsecutiveFail = 0
+	} else {
+		b.counts.TotalFail++
+		b.counts.ConsecutiveFail++
+		b.counts.ConsecutiveSuccess = 0
+	}
+
+	b.calls = append(b.calls, Call{State: state, Err: err})
+}
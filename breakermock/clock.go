@@ -0,0 +1,96 @@
+package breakermock
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sj902/breaker"
+)
+
+// Clock is a fake breaker.Clock whose time only moves with Advance or Set.
+type Clock struct {
+	mutex  sync.Mutex
+	now    time.Time
+	timers []*timer
+}
+
+var _ breaker.Clock = &Clock{}
+
+// NewClock returns a fake clock set to now.
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+// Now implements breaker.Clock.
+func (c *Clock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.now
+}
+
+// NewTimer implements breaker.Clock. The timer fires when the clock is
+// advanced past its deadline.
+func (c *Clock) NewTimer(d time.Duration) breaker.Timer {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	t := &timer{clock: c, deadline: c.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.c <- c.now
+		return t
+	}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// Advance moves the clock forward by d, firing the timers due.
+func (c *Clock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	c.set(c.now.Add(d))
+	c.mutex.Unlock()
+}
+
+// Set moves the clock to now, firing the timers due.
+func (c *Clock) Set(now time.Time) {
+	c.mutex.Lock()
+	c.set(now)
+	c.mutex.Unlock()
+}
+
+func (c *Clock) set(now time.Time) {
+	c.now = now
+
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.deadline.After(now) {
+			pending = append(pending, t)
+			continue
+		}
+		t.c <- now
+	}
+	c.timers = pending
+}
+
+type timer struct {
+	clock    *Clock
+	deadline time.Time
+	c        chan time.Time
+}
+
+func (t *timer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *timer) Stop() bool {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+
+	for i, other := range t.clock.timers {
+		if other == t {
+			t.clock.timers = append(t.clock.timers[:i], t.clock.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
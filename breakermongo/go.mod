@@ -0,0 +1,10 @@
+module github.com/sj902/breaker/breakermongo
+
+go 1.21
+
+require (
+	github.com/sj902/breaker v0.0.0
+	go.mongodb.org/mongo-driver v1.17.1
+)
+
+replace github.com/sj902/breaker => ../
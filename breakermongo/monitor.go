@@ -0,0 +1,116 @@
+// Package breakermongo tracks MongoDB command failures per server with circuit breakers.
+package breakermongo
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/event"
+
+	"github.com/sj902/breaker"
+)
+
+// Monitor records the outcome of MongoDB commands in a circuit breaker per
+// server address. Install it with Command and gate operations with Allow.
+type Monitor struct {
+	// Settings are used to create the circuit breaker of every server, named after its address.
+	Settings breaker.Settings
+	// IsFailure reports whether a failed command counts as a failure. All
+	// failed commands count if nil.
+	IsFailure func(evt *event.CommandFailedEvent) bool
+
+	mutex    sync.Mutex
+	breakers map[string]*breaker.CircuitBreaker
+	pending  map[int64]func(bool)
+}
+
+// Command returns a command monitor recording outcomes in m, for use with
+// options.Client().SetMonitor. next, if non-nil, is called after m.
+func (m *Monitor) Command(next *event.CommandMonitor) *event.CommandMonitor {
+	if next == nil {
+		next = &event.CommandMonitor{}
+	}
+
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, evt *event.CommandStartedEvent) {
+			m.started(evt.RequestID, address(evt.ConnectionID))
+			if next.Started != nil {
+				next.Started(ctx, evt)
+			}
+		},
+		Succeeded: func(ctx context.Context, evt *event.CommandSucceededEvent) {
+			m.finished(evt.RequestID, true)
+			if next.Succeeded != nil {
+				next.Succeeded(ctx, evt)
+			}
+		},
+		Failed: func(ctx context.Context, evt *event.CommandFailedEvent) {
+			m.finished(evt.RequestID, m.IsFailure != nil && !m.IsFailure(evt))
+			if next.Failed != nil {
+				next.Failed(ctx, evt)
+			}
+		},
+	}
+}
+
+// Allow returns breaker.ErrOpenState if the circuit breaker of the server at addr is open.
+func (m *Monitor) Allow(addr string) error {
+	if m.Breaker(addr).State() == breaker.StateOpen {
+		return breaker.ErrOpenState
+	}
+	return nil
+}
+
+// Breaker returns the circuit breaker of the server at addr, creating it on first use.
+func (m *Monitor) Breaker(addr string) *breaker.CircuitBreaker {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.breakers == nil {
+		m.breakers = make(map[string]*breaker.CircuitBreaker)
+	}
+
+	cb, ok := m.breakers[addr]
+	if !ok {
+		st := m.Settings
+		st.Name = addr
+		cb = breaker.NewCircuitBreaker(st)
+		m.breakers[addr] = cb
+	}
+	return cb
+}
+
+func (m *Monitor) started(requestID int64, addr string) {
+	done, err := m.Breaker(addr).Allow()
+	if err != nil {
+		return
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.pending == nil {
+		m.pending = make(map[int64]func(bool))
+	}
+	m.pending[requestID] = done
+}
+
+func (m *Monitor) finished(requestID int64, success bool) {
+	m.mutex.Lock()
+	done, ok := m.pending[requestID]
+	delete(m.pending, requestID)
+	m.mutex.Unlock()
+
+	if ok {
+		done(success)
+	}
+}
+
+// address strips the connection number from a connection ID such as "db1:27017[-12]".
+func address(connectionID string) string {
+	if i := strings.LastIndex(connectionID, "[-"); i >= 0 {
+		return connectionID[:i]
+	}
+	return connectionID
+}
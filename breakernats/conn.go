@@ -0,0 +1,118 @@
+// Package breakernats runs NATS requests and publishes through circuit breakers.
+package breakernats
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/sj902/breaker"
+)
+
+// RejectionError is returned when a circuit breaker rejects a request or publish.
+type RejectionError struct {
+	Subject string
+	Breaker string
+	Err     error
+}
+
+func (e *RejectionError) Error() string {
+	return fmt.Sprintf("nats %s: circuit breaker %s: %v", e.Subject, e.Breaker, e.Err)
+}
+
+func (e *RejectionError) Unwrap() error {
+	return e.Err
+}
+
+// Conn is a nats.Conn running requests and publishes through the circuit
+// breaker of their subject key.
+type Conn struct {
+	*nats.Conn
+	// Settings are used to create the circuit breaker of every key, named after the key.
+	Settings breaker.Settings
+	// Key returns the key of the circuit breaker for a subject, KeyByPrefix(1) if nil.
+	Key func(subject string) string
+
+	mutex    sync.Mutex
+	breakers map[string]*breaker.CircuitBreaker
+}
+
+// KeyByPrefix keys circuit breakers by the first n tokens of the subject, so
+// that "orders.create" and "orders.cancel" share a breaker with KeyByPrefix(1).
+func KeyByPrefix(n int) func(subject string) string {
+	return func(subject string) string {
+		tokens := strings.SplitN(subject, ".", n+1)
+		if len(tokens) > n {
+			tokens = tokens[:n]
+		}
+		return strings.Join(tokens, ".")
+	}
+}
+
+// Request implements nats.Conn.Request.
+func (c *Conn) Request(subj string, data []byte, timeout time.Duration) (*nats.Msg, error) {
+	return do(c, subj, func() (*nats.Msg, error) {
+		return c.Conn.Request(subj, data, timeout)
+	})
+}
+
+// RequestWithContext implements nats.Conn.RequestWithContext.
+func (c *Conn) RequestWithContext(ctx context.Context, subj string, data []byte) (*nats.Msg, error) {
+	return do(c, subj, func() (*nats.Msg, error) {
+		return c.Conn.RequestWithContext(ctx, subj, data)
+	})
+}
+
+// Publish implements nats.Conn.Publish.
+func (c *Conn) Publish(subj string, data []byte) error {
+	_, err := do(c, subj, func() (struct{}, error) {
+		return struct{}{}, c.Conn.Publish(subj, data)
+	})
+	return err
+}
+
+// Breaker returns the circuit breaker of subject, creating it on first use.
+func (c *Conn) Breaker(subject string) *breaker.CircuitBreaker {
+	key := c.key(subject)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.breakers == nil {
+		c.breakers = make(map[string]*breaker.CircuitBreaker)
+	}
+
+	cb, ok := c.breakers[key]
+	if !ok {
+		st := c.Settings
+		st.Name = key
+		cb = breaker.NewCircuitBreaker(st)
+		c.breakers[key] = cb
+	}
+	return cb
+}
+
+func (c *Conn) key(subject string) string {
+	if c.Key == nil {
+		return KeyByPrefix(1)(subject)
+	}
+	return c.Key(subject)
+}
+
+func do[T any](c *Conn, subj string, req func() (T, error)) (T, error) {
+	cb := c.Breaker(subj)
+
+	done, err := cb.Allow()
+	if err != nil {
+		var zero T
+		return zero, &RejectionError{Subject: subj, Breaker: cb.Name(), Err: err}
+	}
+
+	res, err := req()
+	done(err == nil)
+	return res, err
+}
@@ -0,0 +1,68 @@
+// Package breakernet guards connection establishment with circuit breakers.
+package breakernet
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+
+	"github.com/sj902/breaker"
+)
+
+// Dialer dials connections through a circuit breaker per target address, so
+// that connection attempts to a dead host are cut off before reaching the
+// network. It can be used as the DialContext of an http.Transport or with
+// grpc.WithContextDialer.
+type Dialer struct {
+	// Base dials the connections, (&net.Dialer{}).DialContext if nil.
+	Base func(ctx context.Context, network, address string) (net.Conn, error)
+	// Settings are used to create the circuit breaker of every address, named after the address.
+	Settings breaker.Settings
+	// MaxBreakers bounds the number of circuit breakers kept, evicting the
+	// least recently used one beyond it. No limit if zero.
+	MaxBreakers int
+
+	once     sync.Once
+	registry *breaker.Registry
+}
+
+// DialContext dials address on network. Failing to connect counts as a
+// failure; a dial canceled through ctx is ignored.
+func (d *Dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	done, err := d.Breaker(address).AllowOutcome()
+	if err != nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: err}
+	}
+
+	conn, err := d.base()(ctx, network, address)
+	switch {
+	case err == nil:
+		done(breaker.OutcomeSuccess)
+	case errors.Is(err, context.Canceled):
+		done(breaker.OutcomeIgnored)
+	default:
+		done(breaker.OutcomeFailure)
+	}
+	return conn, err
+}
+
+// Breaker returns the circuit breaker of address, creating it on first use.
+func (d *Dialer) Breaker(address string) *breaker.CircuitBreaker {
+	return d.Registry().Get(address)
+}
+
+// Registry returns the registry holding the circuit breakers of d.
+func (d *Dialer) Registry() *breaker.Registry {
+	d.once.Do(func() {
+		d.registry = breaker.NewBoundedRegistry(d.Settings, d.MaxBreakers)
+	})
+	return d.registry
+}
+
+func (d *Dialer) base() func(ctx context.Context, network, address string) (net.Conn, error) {
+	if d.Base == nil {
+		return (&net.Dialer{}).DialContext
+	}
+	return d.Base
+}
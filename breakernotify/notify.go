@@ -0,0 +1,122 @@
+// Package breakernotify sends notifications when circuit breakers trip and recover.
+package breakernotify
+
+import (
+	"context"
+	"time"
+
+	"github.com/sj902/breaker"
+)
+
+const (
+	defaultMaxAttempts = 5
+	defaultTimeout     = 10 * time.Second
+)
+
+var defaultBackoff = breaker.ExponentialBackoff{Initial: time.Second, Max: time.Minute}
+
+// Notification describes a trip, To is "open", or a recovery, To is "closed".
+type Notification struct {
+	Name   string         `json:"name"`
+	From   string         `json:"from"`
+	To     string         `json:"to"`
+	Counts breaker.Counts `json:"counts"`
+	// Error is the error that opened the circuit, if any.
+	Error string    `json:"error,omitempty"`
+	Time  time.Time `json:"time"`
+}
+
+// Notifier delivers a notification.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// Dispatcher delivers the trips and recoveries of circuit breakers to
+// Notifiers, retrying failed deliveries with backoff.
+type Dispatcher struct {
+	Notifiers []Notifier
+	// MaxAttempts is the number of delivery attempts per notifier, 5 if zero.
+	MaxAttempts int
+	// Backoff is the delay between attempts, exponential from 1s up to 1m if nil.
+	Backoff breaker.BackoffPolicy
+	// Timeout bounds every attempt, 10s if zero.
+	Timeout time.Duration
+	// OnError, if non-nil, is called when a notifier failed all its attempts.
+	OnError func(n Notification, err error)
+}
+
+// Watch delivers the trips and recoveries of cb until ctx is done.
+func (d *Dispatcher) Watch(ctx context.Context, cb *breaker.CircuitBreaker) {
+	events := cb.EventsContext(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			if e.Type != breaker.EventTransition || e.To == breaker.StateHalfOpen {
+				continue
+			}
+
+			n := Notification{
+				Name:   e.Name,
+				From:   e.From.String(),
+				To:     e.To.String(),
+				Counts: e.Counts,
+				Time:   e.Time,
+			}
+			if err := cb.TripError(); err != nil && e.To == breaker.StateOpen {
+				n.Error = err.Error()
+			}
+			d.Dispatch(ctx, n)
+		}
+	}
+}
+
+// Dispatch delivers n to every notifier in the background.
+func (d *Dispatcher) Dispatch(ctx context.Context, n Notification) {
+	for _, notifier := range d.Notifiers {
+		go d.deliver(ctx, notifier, n)
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, notifier Notifier, n Notification) {
+	attempts := d.MaxAttempts
+	if attempts <= 0 {
+		attempts = defaultMaxAttempts
+	}
+	var backoff breaker.BackoffPolicy = defaultBackoff
+	if d.Backoff != nil {
+		backoff = d.Backoff
+	}
+	timeout := d.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			timer := time.NewTimer(backoff.Backoff(attempt - 1))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+
+		attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+		err = notifier.Notify(attemptCtx, n)
+		cancel()
+		if err == nil {
+			return
+		}
+	}
+
+	if d.OnError != nil {
+		d.OnError(n, err)
+	}
+}
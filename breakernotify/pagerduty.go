@@ -0,0 +1,64 @@
+package breakernotify
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+const pagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDuty is a Notifier triggering a PagerDuty Events API v2 alert when a
+// circuit breaker opens and resolving it when the breaker closes.
+type PagerDuty struct {
+	RoutingKey string
+	// Source of the alerts, "breaker" if empty.
+	Source string
+	// Severity of the alerts, "error" if empty.
+	Severity string
+	// Client sends the requests, http.DefaultClient if nil.
+	Client *http.Client
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string       `json:"summary"`
+	Source        string       `json:"source"`
+	Severity      string       `json:"severity"`
+	Timestamp     string       `json:"timestamp"`
+	CustomDetails Notification `json:"custom_details"`
+}
+
+func (p *PagerDuty) Notify(ctx context.Context, n Notification) error {
+	event := pagerDutyEvent{
+		RoutingKey:  p.RoutingKey,
+		EventAction: "resolve",
+		DedupKey:    "breaker/" + n.Name,
+	}
+
+	if n.To == "open" {
+		source, severity := p.Source, p.Severity
+		if source == "" {
+			source = "breaker"
+		}
+		if severity == "" {
+			severity = "error"
+		}
+
+		event.EventAction = "trigger"
+		event.Payload = &pagerDutyPayload{
+			Summary:       message(n),
+			Source:        source,
+			Severity:      severity,
+			Timestamp:     n.Time.Format(time.RFC3339),
+			CustomDetails: n,
+		}
+	}
+	return postJSON(ctx, p.Client, pagerDutyURL, event)
+}
@@ -0,0 +1,35 @@
+package breakernotify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Slack is a Notifier posting messages to a Slack incoming webhook.
+type Slack struct {
+	WebhookURL string
+	// Client sends the requests, http.DefaultClient if nil.
+	Client *http.Client
+}
+
+func (s *Slack) Notify(ctx context.Context, n Notification) error {
+	icon := ":white_check_mark:"
+	if n.To == "open" {
+		icon = ":rotating_light:"
+	}
+	return postJSON(ctx, s.Client, s.WebhookURL, map[string]string{"text": icon + " " + message(n)})
+}
+
+func message(n Notification) string {
+	if n.To != "open" {
+		return fmt.Sprintf("circuit breaker %s recovered, %s to %s", n.Name, n.From, n.To)
+	}
+
+	msg := fmt.Sprintf("circuit breaker %s opened after %d failures out of %d requests",
+		n.Name, n.Counts.TotalFail, n.Counts.Requests)
+	if n.Error != "" {
+		msg += ": " + n.Error
+	}
+	return msg
+}
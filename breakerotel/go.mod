@@ -0,0 +1,11 @@
+module github.com/sj902/breaker/breakerotel
+
+go 1.21
+
+require (
+	github.com/sj902/breaker v0.0.0
+	go.opentelemetry.io/otel v1.24.0
+	go.opentelemetry.io/otel/trace v1.24.0
+)
+
+replace github.com/sj902/breaker => ../
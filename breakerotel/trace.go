@@ -0,0 +1,73 @@
+// Package breakerotel records circuit breaker activity in OpenTelemetry traces.
+package breakerotel
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/sj902/breaker"
+)
+
+// Attribute keys set by Execute.
+const (
+	NameKey    = attribute.Key("breaker.name")
+	StateKey   = attribute.Key("breaker.state")
+	OutcomeKey = attribute.Key("breaker.outcome")
+	FromKey    = attribute.Key("breaker.from")
+	ToKey      = attribute.Key("breaker.to")
+)
+
+// Execute runs req through cb and records it in the trace of ctx. With a
+// non-nil tracer it starts a span named after the breaker around the call,
+// otherwise it adds an event to the span already in ctx. Either way the
+// breaker name, its state at admission and the outcome are recorded, along
+// with a "breaker.transition" event if the call changed the state of cb. The
+// outcome is "rejected" if cb did not run req, otherwise the classification
+// of its error by cb: success, failure, ignored or fatal.
+func Execute[T any](ctx context.Context, tracer trace.Tracer, cb *breaker.CircuitBreaker, req func(ctx context.Context) (T, error)) (T, error) {
+	span := trace.SpanFromContext(ctx)
+	if tracer != nil {
+		ctx, span = tracer.Start(ctx, "breaker "+cb.Name())
+		defer span.End()
+	}
+
+	before := cb.State()
+	ran := false
+	v, err := cb.ExecuteContext(ctx, func(ctx context.Context) (interface{}, error) {
+		ran = true
+		return req(ctx)
+	})
+	after := cb.State()
+	res, _ := v.(T)
+
+	outcome := "rejected"
+	if ran {
+		outcome = cb.Classify(err).String()
+	}
+	attrs := []attribute.KeyValue{
+		NameKey.String(cb.Name()),
+		StateKey.String(before.String()),
+		OutcomeKey.String(outcome),
+	}
+	if tracer != nil {
+		span.SetAttributes(attrs...)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+	} else {
+		span.AddEvent("breaker.execute", trace.WithAttributes(attrs...))
+	}
+
+	if after != before {
+		span.AddEvent("breaker.transition", trace.WithAttributes(
+			NameKey.String(cb.Name()),
+			FromKey.String(before.String()),
+			ToKey.String(after.String()),
+		))
+	}
+	return res, err
+}
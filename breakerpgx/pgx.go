@@ -0,0 +1,154 @@
+// Package breakerpgx guards pgx connection pools with circuit breakers.
+package breakerpgx
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sj902/breaker"
+)
+
+// IsFailure reports whether err means the database is unhealthy: connection
+// errors and PostgreSQL errors of the connection exception (08), insufficient
+// resources (53), operator intervention (57), system error (58) and internal
+// error (XX) classes. Other errors, such as constraint violations and
+// pgx.ErrNoRows, are caused by the query and count as successes.
+func IsFailure(err error) bool {
+	if err == nil || errors.Is(err, pgx.ErrNoRows) || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && len(pgErr.Code) >= 2 {
+		switch pgErr.Code[:2] {
+		case "08", "53", "57", "58", "XX":
+			return true
+		}
+		return false
+	}
+	return true
+}
+
+// Tracer is a pgx.QueryTracer recording the outcome of every query in Breaker.
+// Queries rejected by Breaker, e.g. beyond the probes allowed while half-open,
+// still run but are not recorded; use Pool to reject them up front.
+type Tracer struct {
+	Breaker *breaker.CircuitBreaker
+	// IsFailure reports whether a query error counts as a failure. IsFailure if nil.
+	IsFailure func(error) bool
+}
+
+type doneKey struct{}
+
+func (t *Tracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, _ pgx.TraceQueryStartData) context.Context {
+	done, err := t.Breaker.Allow()
+	if err != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, doneKey{}, done)
+}
+
+func (t *Tracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	done, ok := ctx.Value(doneKey{}).(func(bool))
+	if !ok {
+		return
+	}
+
+	isFailure := t.IsFailure
+	if isFailure == nil {
+		isFailure = IsFailure
+	}
+	done(!isFailure(data.Err))
+}
+
+// Config installs a Tracer for cb on cfg and rejects new connections while cb is open.
+func Config(cfg *pgxpool.Config, cb *breaker.CircuitBreaker) {
+	cfg.ConnConfig.Tracer = &Tracer{Breaker: cb}
+
+	beforeConnect := cfg.BeforeConnect
+	cfg.BeforeConnect = func(ctx context.Context, cc *pgx.ConnConfig) error {
+		if cb.State() == breaker.StateOpen {
+			return breaker.ErrOpenState
+		}
+		if beforeConnect != nil {
+			return beforeConnect(ctx, cc)
+		}
+		return nil
+	}
+}
+
+// Pool is a pgxpool.Pool failing fast with breaker.ErrOpenState while Breaker
+// is open. Breaker is expected to be driven by the Tracer installed by Config.
+type Pool struct {
+	*pgxpool.Pool
+	Breaker *breaker.CircuitBreaker
+}
+
+// New creates a Pool for connString guarded by cb.
+func New(ctx context.Context, connString string, cb *breaker.CircuitBreaker) (*Pool, error) {
+	cfg, err := pgxpool.ParseConfig(connString)
+	if err != nil {
+		return nil, err
+	}
+	Config(cfg, cb)
+
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Pool{Pool: pool, Breaker: cb}, nil
+}
+
+func (p *Pool) check() error {
+	if p.Breaker.State() == breaker.StateOpen {
+		return breaker.ErrOpenState
+	}
+	return nil
+}
+
+func (p *Pool) Acquire(ctx context.Context) (*pgxpool.Conn, error) {
+	if err := p.check(); err != nil {
+		return nil, err
+	}
+	return p.Pool.Acquire(ctx)
+}
+
+func (p *Pool) Begin(ctx context.Context) (pgx.Tx, error) {
+	if err := p.check(); err != nil {
+		return nil, err
+	}
+	return p.Pool.Begin(ctx)
+}
+
+func (p *Pool) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	if err := p.check(); err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return p.Pool.Exec(ctx, sql, args...)
+}
+
+func (p *Pool) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	if err := p.check(); err != nil {
+		return nil, err
+	}
+	return p.Pool.Query(ctx, sql, args...)
+}
+
+func (p *Pool) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	if err := p.check(); err != nil {
+		return errRow{err}
+	}
+	return p.Pool.QueryRow(ctx, sql, args...)
+}
+
+type errRow struct {
+	err error
+}
+
+func (r errRow) Scan(...any) error {
+	return r.err
+}
@@ -0,0 +1,101 @@
+// Package breakerprom exports circuit breaker metrics to Prometheus.
+package breakerprom
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/sj902/breaker"
+)
+
+var states = []breaker.State{breaker.StateClosed, breaker.StateHalfOpen, breaker.StateOpen}
+
+var (
+	stateDesc = prometheus.NewDesc("circuit_breaker_state",
+		"Whether the circuit breaker is in the state, 1 or 0.", []string{"name", "state"}, nil)
+	requestsDesc = prometheus.NewDesc("circuit_breaker_requests_total",
+		"Requests admitted by the circuit breaker.", []string{"name"}, nil)
+	successesDesc = prometheus.NewDesc("circuit_breaker_successes_total",
+		"Requests that completed successfully.", []string{"name"}, nil)
+	failuresDesc = prometheus.NewDesc("circuit_breaker_failures_total",
+		"Requests that completed with a failure.", []string{"name"}, nil)
+	rejectionsDesc = prometheus.NewDesc("circuit_breaker_rejections_total",
+		"Requests rejected by the circuit breaker.", []string{"name"}, nil)
+	transitionsDesc = prometheus.NewDesc("circuit_breaker_transitions_total",
+		"Transitions of the circuit breaker into the state.", []string{"name", "state"}, nil)
+)
+
+// Collector is a prometheus.Collector exporting the state and lifetime
+// metrics of a set of circuit breakers, labeled by breaker name.
+type Collector struct {
+	// Registry, if non-nil, adds all its circuit breakers, including the ones
+	// created later, to the ones given to NewCollector and Add.
+	Registry *breaker.Registry
+
+	mutex    sync.Mutex
+	breakers []*breaker.CircuitBreaker
+}
+
+var _ prometheus.Collector = &Collector{}
+
+// NewCollector returns a Collector for cbs.
+func NewCollector(cbs ...*breaker.CircuitBreaker) *Collector {
+	return &Collector{breakers: cbs}
+}
+
+// Add adds cb to the circuit breakers exported by c.
+func (c *Collector) Add(cb *breaker.CircuitBreaker) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.breakers = append(c.breakers, cb)
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- stateDesc
+	ch <- requestsDesc
+	ch <- successesDesc
+	ch <- failuresDesc
+	ch <- rejectionsDesc
+	ch <- transitionsDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.mutex.Lock()
+	cbs := append([]*breaker.CircuitBreaker(nil), c.breakers...)
+	c.mutex.Unlock()
+	if c.Registry != nil {
+		cbs = append(cbs, c.Registry.Breakers()...)
+	}
+
+	// A breaker both added and in the registry must be exported once, the
+	// Prometheus registry rejects duplicate series.
+	seen := make(map[*breaker.CircuitBreaker]bool, len(cbs))
+	for _, cb := range cbs {
+		if seen[cb] {
+			continue
+		}
+		seen[cb] = true
+
+		name := cb.Name()
+		state := cb.State()
+		m := cb.Metrics()
+
+		for _, s := range states {
+			var v float64
+			if s == state {
+				v = 1
+			}
+			ch <- prometheus.MustNewConstMetric(stateDesc, prometheus.GaugeValue, v, name, s.String())
+			ch <- prometheus.MustNewConstMetric(transitionsDesc, prometheus.CounterValue, float64(m.Transitions[s]), name, s.String())
+		}
+
+		ch <- prometheus.MustNewConstMetric(requestsDesc, prometheus.CounterValue, float64(m.Requests), name)
+		ch <- prometheus.MustNewConstMetric(successesDesc, prometheus.CounterValue, float64(m.Successes), name)
+		ch <- prometheus.MustNewConstMetric(failuresDesc, prometheus.CounterValue, float64(m.Failures), name)
+		ch <- prometheus.MustNewConstMetric(rejectionsDesc, prometheus.CounterValue, float64(m.Rejections), name)
+	}
+}
@@ -0,0 +1,15 @@
+module github.com/sj902/breaker/breakerredis
+
+go 1.21
+
+require (
+	github.com/redis/go-redis/v9 v9.6.1
+	github.com/sj902/breaker v0.0.0
+)
+
+require (
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+)
+
+replace github.com/sj902/breaker => ../
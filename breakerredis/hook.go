@@ -0,0 +1,123 @@
+// Package breakerredis provides a go-redis hook running commands through circuit breakers.
+package breakerredis
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/sj902/breaker"
+)
+
+// Hook is a redis.Hook running commands and pipelines through the circuit
+// breaker of their key. Rejected commands fail with the breaker error.
+type Hook struct {
+	// Settings are used to create the circuit breaker of every key, named after the key.
+	Settings breaker.Settings
+	// Key returns the key of the circuit breaker for a command. If nil, all
+	// commands share one circuit breaker named after Settings.Name. Pipelines
+	// use the "pipeline" key whenever Key is set.
+	Key func(cmd redis.Cmder) string
+	// IsFailure reports whether a command error counts as a failure. IsFailure if nil.
+	IsFailure func(error) bool
+
+	mutex    sync.Mutex
+	breakers map[string]*breaker.CircuitBreaker
+}
+
+var _ redis.Hook = &Hook{}
+
+// IsFailure reports whether err counts as a failure, that is any error other
+// than redis.Nil and context cancellation.
+func IsFailure(err error) bool {
+	return err != nil && !errors.Is(err, redis.Nil) && !errors.Is(err, context.Canceled)
+}
+
+// KeyByCommand keys circuit breakers by command name, e.g. "get" or "evalsha".
+func KeyByCommand(cmd redis.Cmder) string {
+	return cmd.Name()
+}
+
+// Breaker returns the circuit breaker of key, creating it on first use.
+func (h *Hook) Breaker(key string) *breaker.CircuitBreaker {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.breakers == nil {
+		h.breakers = make(map[string]*breaker.CircuitBreaker)
+	}
+
+	cb, ok := h.breakers[key]
+	if !ok {
+		st := h.Settings
+		if h.Key != nil {
+			st.Name = key
+		}
+		cb = breaker.NewCircuitBreaker(st)
+		h.breakers[key] = cb
+	}
+	return cb
+}
+
+func (h *Hook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h *Hook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		var key string
+		if h.Key != nil {
+			key = h.Key(cmd)
+		}
+
+		err := h.run(key, func() error {
+			return next(ctx, cmd)
+		})
+		if isRejection(err) {
+			cmd.SetErr(err)
+		}
+		return err
+	}
+}
+
+func (h *Hook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		var key string
+		if h.Key != nil {
+			key = "pipeline"
+		}
+
+		err := h.run(key, func() error {
+			return next(ctx, cmds)
+		})
+		if isRejection(err) {
+			for _, cmd := range cmds {
+				cmd.SetErr(err)
+			}
+		}
+		return err
+	}
+}
+
+func (h *Hook) run(key string, req func() error) error {
+	done, err := h.Breaker(key).Allow()
+	if err != nil {
+		return err
+	}
+
+	err = req()
+
+	isFailure := h.IsFailure
+	if isFailure == nil {
+		isFailure = IsFailure
+	}
+	done(!isFailure(err))
+	return err
+}
+
+func isRejection(err error) bool {
+	return errors.Is(err, breaker.ErrOpenState) || errors.Is(err, breaker.ErrTooManyRequests) ||
+		errors.Is(err, breaker.ErrBulkheadFull) || errors.Is(err, breaker.ErrQueueTimeout)
+}
@@ -0,0 +1,147 @@
+package breakerredis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/sj902/breaker"
+)
+
+// State is a breaker.SharedState keeping one JSON snapshot per circuit
+// breaker in Redis, updated atomically by Lua scripts, so that all replicas
+// of a service trip and recover together:
+//
+//	st.SharedState = &breakerredis.State{Client: rdb, Prefix: "myservice:breaker:"}
+type State struct {
+	Client redis.Cmdable
+	// Prefix is prepended to the breaker name to form the key.
+	Prefix string
+	// TTL expires the keys of breakers that are no longer used. Zero keeps them forever.
+	TTL time.Duration
+}
+
+var _ breaker.SharedState = &State{}
+
+// addScript merges the delta in ARGV[2] into the snapshot at KEYS[1] if it is
+// at generation ARGV[1] and returns the snapshot.
+var addScript = redis.NewScript(`
+local raw = redis.call("GET", KEYS[1])
+local s
+if raw then
+	s = cjson.decode(raw)
+else
+	s = {name = ARGV[4], state = "closed", generation = 0, trips = 0, counts = {
+		Requests = 0, TotalSuccess = 0, TotalFail = 0, TotalSlow = 0,
+		ConsecutiveSuccess = 0, ConsecutiveFail = 0}}
+end
+
+if s.generation == tonumber(ARGV[1]) then
+	local c, d = s.counts, cjson.decode(ARGV[2])
+	c.Requests = c.Requests + d.Requests
+	c.TotalSuccess = c.TotalSuccess + d.TotalSuccess
+	c.TotalFail = c.TotalFail + d.TotalFail
+	c.TotalSlow = c.TotalSlow + d.TotalSlow
+	if type(d.Errors) == "table" then
+		if type(c.Errors) ~= "table" then
+			c.Errors = {}
+		end
+		for class, n in pairs(d.Errors) do
+			c.Errors[class] = (c.Errors[class] or 0) + n
+		end
+	end
+
+	if d.ConsecutiveFail > 0 then
+		if d.TotalSuccess == 0 then
+			c.ConsecutiveFail = c.ConsecutiveFail + d.ConsecutiveFail
+		else
+			c.ConsecutiveFail = d.ConsecutiveFail
+		end
+		c.ConsecutiveSuccess = 0
+	elseif d.ConsecutiveSuccess > 0 then
+		if d.TotalFail == 0 then
+			c.ConsecutiveSuccess = c.ConsecutiveSuccess + d.ConsecutiveSuccess
+		else
+			c.ConsecutiveSuccess = d.ConsecutiveSuccess
+		end
+		c.ConsecutiveFail = 0
+	end
+
+	raw = cjson.encode(s)
+	redis.call("SET", KEYS[1], raw)
+elseif not raw then
+	raw = cjson.encode(s)
+end
+
+if tonumber(ARGV[3]) > 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[3])
+end
+return raw
+`)
+
+// casScript replaces the snapshot at KEYS[1] with ARGV[2] if it is at
+// generation ARGV[1], returning 1 if it did.
+var casScript = redis.NewScript(`
+local raw = redis.call("GET", KEYS[1])
+local generation = 0
+if raw then
+	generation = cjson.decode(raw).generation
+end
+if generation ~= tonumber(ARGV[1]) then
+	return 0
+end
+
+redis.call("SET", KEYS[1], ARGV[2])
+if tonumber(ARGV[3]) > 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[3])
+end
+return 1
+`)
+
+// Load implements breaker.SharedState.
+func (s *State) Load(ctx context.Context, name string) (breaker.Snapshot, bool, error) {
+	raw, err := s.Client.Get(ctx, s.Prefix+name).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return breaker.Snapshot{}, false, nil
+	}
+	if err != nil {
+		return breaker.Snapshot{}, false, err
+	}
+
+	var snap breaker.Snapshot
+	err = json.Unmarshal(raw, &snap)
+	return snap, err == nil, err
+}
+
+// Add implements breaker.SharedState.
+func (s *State) Add(ctx context.Context, name string, generation int, delta breaker.Counts) (breaker.Snapshot, error) {
+	delta.Buckets = nil
+	d, err := json.Marshal(delta)
+	if err != nil {
+		return breaker.Snapshot{}, err
+	}
+
+	raw, err := addScript.Run(ctx, s.Client, []string{s.Prefix + name}, generation, d, s.TTL.Milliseconds(), name).Text()
+	if err != nil {
+		return breaker.Snapshot{}, err
+	}
+
+	var snap breaker.Snapshot
+	err = json.Unmarshal([]byte(raw), &snap)
+	return snap, err
+}
+
+// CompareAndSwap implements breaker.SharedState.
+func (s *State) CompareAndSwap(ctx context.Context, name string, old int, snap breaker.Snapshot) (bool, error) {
+	snap.Counts.Buckets = nil
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return false, err
+	}
+
+	swapped, err := casScript.Run(ctx, s.Client, []string{s.Prefix + name}, old, data, s.TTL.Milliseconds()).Int()
+	return swapped == 1, err
+}
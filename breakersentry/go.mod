@@ -0,0 +1,15 @@
+module github.com/sj902/breaker/breakersentry
+
+go 1.21
+
+require (
+	github.com/getsentry/sentry-go v0.29.0
+	github.com/sj902/breaker v0.0.0
+)
+
+require (
+	golang.org/x/sys v0.18.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+)
+
+replace github.com/sj902/breaker => ../
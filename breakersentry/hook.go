@@ -0,0 +1,83 @@
+// Package breakersentry reports circuit breaker transitions to Sentry.
+package breakersentry
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/getsentry/sentry-go"
+
+	"github.com/sj902/breaker"
+)
+
+// Hook records a breadcrumb for every state change of a circuit breaker and
+// captures an event, with the counts and the error that tripped it, when it opens.
+type Hook struct {
+	// Hub receives the breadcrumbs and events, sentry.CurrentHub() if nil.
+	Hub *sentry.Hub
+}
+
+// Watch reports the transitions of cb until ctx is done.
+func (h *Hook) Watch(ctx context.Context, cb *breaker.CircuitBreaker) {
+	events := cb.EventsContext(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			if e.Type == breaker.EventTransition {
+				h.report(e, cb.TripError())
+			}
+		}
+	}
+}
+
+func (h *Hook) report(e breaker.Event, tripErr error) {
+	hub := h.Hub
+	if hub == nil {
+		hub = sentry.CurrentHub()
+	}
+
+	level := sentry.LevelInfo
+	if e.To == breaker.StateOpen {
+		level = sentry.LevelWarning
+	}
+	hub.AddBreadcrumb(&sentry.Breadcrumb{
+		Type:     "default",
+		Category: "circuit_breaker",
+		Message:  fmt.Sprintf("%s: %s -> %s", e.Name, e.From, e.To),
+		Data: map[string]interface{}{
+			"name": e.Name,
+			"from": e.From.String(),
+			"to":   e.To.String(),
+		},
+		Level:     level,
+		Timestamp: e.Time,
+	}, nil)
+
+	if e.To != breaker.StateOpen {
+		return
+	}
+
+	hub.WithScope(func(scope *sentry.Scope) {
+		scope.SetLevel(sentry.LevelError)
+		scope.SetTag("circuit_breaker", e.Name)
+		scope.SetContext("circuit_breaker", sentry.Context{
+			"name":                e.Name,
+			"from":                e.From.String(),
+			"requests":            e.Counts.Requests,
+			"failures":            e.Counts.TotalFail,
+			"successes":           e.Counts.TotalSuccess,
+			"consecutiveFailures": e.Counts.ConsecutiveFail,
+			"errors":              e.Counts.Errors,
+		})
+
+		if tripErr != nil {
+			scope.SetExtra("trip_error", tripErr.Error())
+		}
+		hub.CaptureMessage(fmt.Sprintf("circuit breaker %s opened", e.Name))
+	})
+}
@@ -0,0 +1,170 @@
+package breakersim
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sj902/breaker"
+)
+
+// Record is a recorded call to a dependency.
+type Record struct {
+	Time    time.Time
+	Latency time.Duration
+	// Err is the error of the call, empty for a success.
+	Err string
+}
+
+// UnmarshalJSON implements json.Unmarshaler. The latency is a duration such
+// as "12ms" or a number of milliseconds.
+func (r *Record) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Time    time.Time       `json:"time"`
+		Latency json.RawMessage `json:"latency"`
+		Err     string          `json:"error"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	latency := strings.Trim(string(v.Latency), `"`)
+	if latency == "null" {
+		latency = ""
+	}
+	d, err := parseLatency(latency)
+	if err != nil {
+		return err
+	}
+
+	*r = Record{Time: v.Time, Latency: d, Err: v.Err}
+	return nil
+}
+
+// Replay replays recorded calls against st and reports when the circuit
+// breaker would have tripped and recovered. Calls are replayed in time order,
+// with the timeline starting at the first one.
+func Replay(st breaker.Settings, records []Record) Result {
+	if len(records) == 0 {
+		return run(st, 0, func(func(time.Duration, Outcome)) {})
+	}
+
+	records = append([]Record(nil), records...)
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Time.Before(records[j].Time) })
+	first := records[0].Time
+	duration := records[len(records)-1].Time.Sub(first)
+
+	return run(st, duration, func(call func(elapsed time.Duration, o Outcome)) {
+		for _, r := range records {
+			call(r.Time.Sub(first), Outcome{Fail: r.Err != "", Latency: r.Latency})
+		}
+	})
+}
+
+// ReadRecords reads recorded calls as JSON lines, e.g.
+//
+//	{"time": "2024-05-01T12:00:00.123Z", "latency": "12ms", "error": "connection refused"}
+//
+// or as CSV with a header, taking the time, latency and error columns:
+//
+//	time,latency,error
+//	2024-05-01T12:00:00.123Z,12ms,connection refused
+//
+// Times are RFC 3339; latencies are durations such as "12ms" or milliseconds
+// such as 12.5.
+func ReadRecords(r io.Reader) ([]Record, error) {
+	br := bufio.NewReader(r)
+	head, err := br.Peek(1)
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.Equal(head, []byte("{")) {
+		return readJSON(br)
+	}
+	return readCSV(br)
+}
+
+func readJSON(r io.Reader) ([]Record, error) {
+	var records []Record
+	dec := json.NewDecoder(r)
+	for {
+		var rec Record
+		err := dec.Decode(&rec)
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", len(records)+1, err)
+		}
+		records = append(records, rec)
+	}
+}
+
+func readCSV(r io.Reader) ([]Record, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := map[string]int{"time": -1, "latency": -1, "error": -1}
+	for i, name := range header {
+		if _, ok := columns[strings.ToLower(strings.TrimSpace(name))]; ok {
+			columns[strings.ToLower(strings.TrimSpace(name))] = i
+		}
+	}
+	if columns["time"] < 0 {
+		return nil, errors.New("missing time column")
+	}
+
+	var records []Record
+	for line := 2; ; line++ {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		field := func(name string) string {
+			if i := columns[name]; i >= 0 && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+
+		var rec Record
+		if rec.Time, err = time.Parse(time.RFC3339Nano, field("time")); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if rec.Latency, err = parseLatency(field("latency")); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rec.Err = field("error")
+		records = append(records, rec)
+	}
+}
+
+func parseLatency(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if ms, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(ms * float64(time.Millisecond)), nil
+	}
+	return time.ParseDuration(s)
+}
@@ -0,0 +1,238 @@
+// Package breakersim drives a circuit breaker with synthetic traffic under a
+// fake clock and reports its state timeline, to check settings against
+// failure bursts, gradual degradation or flapping dependencies before
+// production:
+//
+//	res := breakersim.Simulation{
+//		Settings: st,
+//		Pattern:  breakersim.FailureBurst(time.Minute, 30*time.Second, 0.8),
+//		Duration: 5 * time.Minute,
+//		Rate:     50,
+//	}.Run()
+//	fmt.Print(res)
+package breakersim
+
+import (
+	"container/heap"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/sj902/breaker"
+	"github.com/sj902/breaker/breakermock"
+)
+
+const (
+	defaultRate    = 10
+	healthyLatency = 10 * time.Millisecond
+)
+
+// Outcome is the outcome of a simulated call.
+type Outcome struct {
+	Fail    bool
+	Latency time.Duration
+}
+
+// Pattern returns the outcome of a call made at elapsed time since the start
+// of the simulation. It draws random numbers from rnd only, so that
+// simulations are reproducible.
+type Pattern func(elapsed time.Duration, rnd *rand.Rand) Outcome
+
+// Steady fails calls with probability rate and answers after latency.
+func Steady(rate float64, latency time.Duration) Pattern {
+	return func(elapsed time.Duration, rnd *rand.Rand) Outcome {
+		return Outcome{Fail: rnd.Float64() < rate, Latency: latency}
+	}
+}
+
+// FailureBurst is a healthy dependency failing calls with probability rate
+// for length from start.
+func FailureBurst(start, length time.Duration, rate float64) Pattern {
+	return func(elapsed time.Duration, rnd *rand.Rand) Outcome {
+		if elapsed < start || elapsed >= start+length {
+			return Outcome{Latency: healthyLatency}
+		}
+		return Outcome{Fail: rnd.Float64() < rate, Latency: healthyLatency}
+	}
+}
+
+// Degradation is a healthy dependency whose failure rate rises linearly from
+// start to reach maxRate after ramp, and stays there.
+func Degradation(start, ramp time.Duration, maxRate float64) Pattern {
+	return func(elapsed time.Duration, rnd *rand.Rand) Outcome {
+		rate := 0.0
+		switch {
+		case elapsed >= start+ramp:
+			rate = maxRate
+		case elapsed > start:
+			rate = maxRate * float64(elapsed-start) / float64(ramp)
+		}
+		return Outcome{Fail: rnd.Float64() < rate, Latency: healthyLatency}
+	}
+}
+
+// Flapping is a dependency failing every call for down at the start of every period.
+func Flapping(period, down time.Duration) Pattern {
+	return func(elapsed time.Duration, rnd *rand.Rand) Outcome {
+		return Outcome{Fail: elapsed%period < down, Latency: healthyLatency}
+	}
+}
+
+// SlowDown is a healthy dependency answering after latency for length from start.
+func SlowDown(start, length, latency time.Duration) Pattern {
+	return func(elapsed time.Duration, rnd *rand.Rand) Outcome {
+		if elapsed < start || elapsed >= start+length {
+			return Outcome{Latency: healthyLatency}
+		}
+		return Outcome{Latency: latency}
+	}
+}
+
+// Simulation describes a simulation run.
+type Simulation struct {
+	// Settings are the settings under test. Clock is replaced by the fake clock.
+	Settings breaker.Settings
+	Pattern  Pattern
+	Duration time.Duration
+	// Rate is the number of calls per second, evenly spaced, defaults to 10.
+	Rate float64
+	// Seed seeds the random numbers of Pattern.
+	Seed int64
+}
+
+// Transition is a state change of the simulated circuit breaker.
+type Transition struct {
+	Elapsed time.Duration
+	From    breaker.State
+	To      breaker.State
+}
+
+// Result is the outcome of a simulation.
+type Result struct {
+	Timeline []Transition
+	// TimeIn is the time spent in every state.
+	TimeIn   map[breaker.State]time.Duration
+	Calls    int
+	Rejected int
+	// Successes and Failures count the outcomes of the admitted calls.
+	Successes int
+	Failures  int
+	// FailuresSpared counts the rejected calls that would have failed.
+	FailuresSpared int
+	// SuccessesRejected counts the rejected calls that would have succeeded.
+	SuccessesRejected int
+}
+
+// Run runs the simulation. Calls complete after their latency, so that slow
+// calls overlap later ones as they would in production.
+func (s Simulation) Run() Result {
+	rate := s.Rate
+	if rate <= 0 {
+		rate = defaultRate
+	}
+	step := time.Duration(float64(time.Second) / rate)
+	rnd := rand.New(rand.NewSource(s.Seed))
+
+	return run(s.Settings, s.Duration, func(call func(elapsed time.Duration, o Outcome)) {
+		for elapsed := time.Duration(0); elapsed < s.Duration; elapsed += step {
+			call(elapsed, s.Pattern(elapsed, rnd))
+		}
+	})
+}
+
+// run runs a circuit breaker created from st with the calls made by calls,
+// in order of elapsed time, for duration.
+func run(st breaker.Settings, duration time.Duration, calls func(call func(elapsed time.Duration, o Outcome))) Result {
+	start := time.Unix(0, 0).UTC()
+	clock := breakermock.NewClock(start)
+
+	res := Result{TimeIn: make(map[breaker.State]time.Duration)}
+	st.Clock = clock
+	onStateChange := st.OnStateChange
+	st.OnStateChange = func(name string, from breaker.State, to breaker.State) {
+		res.Timeline = append(res.Timeline, Transition{Elapsed: clock.Now().Sub(start), From: from, To: to})
+		if onStateChange != nil {
+			onStateChange(name, from, to)
+		}
+	}
+	cb := breaker.NewCircuitBreaker(st)
+
+	var pending completions
+	complete := func(until time.Duration) {
+		for len(pending) > 0 && pending[0].at <= until {
+			c := heap.Pop(&pending).(completion)
+			clock.Set(start.Add(c.at))
+			c.done(!c.fail)
+		}
+	}
+
+	calls(func(elapsed time.Duration, outcome Outcome) {
+		complete(elapsed)
+		clock.Set(start.Add(elapsed))
+
+		res.Calls++
+		done, err := cb.Allow()
+		switch {
+		case err != nil:
+			res.Rejected++
+			if outcome.Fail {
+				res.FailuresSpared++
+			} else {
+				res.SuccessesRejected++
+			}
+		case outcome.Fail:
+			res.Failures++
+			heap.Push(&pending, completion{at: elapsed + outcome.Latency, done: done, fail: true})
+		default:
+			res.Successes++
+			heap.Push(&pending, completion{at: elapsed + outcome.Latency, done: done})
+		}
+	})
+	complete(1<<63 - 1)
+
+	prev, state := time.Duration(0), breaker.StateClosed
+	for _, t := range res.Timeline {
+		if t.Elapsed > duration {
+			break
+		}
+		res.TimeIn[state] += t.Elapsed - prev
+		prev, state = t.Elapsed, t.To
+	}
+	res.TimeIn[state] += duration - prev
+	return res
+}
+
+// String formats the result as a report.
+func (r Result) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "calls %d, rejected %d, successes %d, failures %d\n", r.Calls, r.Rejected, r.Successes, r.Failures)
+	fmt.Fprintf(&b, "failures spared %d, successes rejected %d\n", r.FailuresSpared, r.SuccessesRejected)
+	fmt.Fprintf(&b, "time closed %v, half-open %v, open %v\n",
+		r.TimeIn[breaker.StateClosed], r.TimeIn[breaker.StateHalfOpen], r.TimeIn[breaker.StateOpen])
+	for _, t := range r.Timeline {
+		fmt.Fprintf(&b, "%10v  %s -> %s\n", t.Elapsed, t.From, t.To)
+	}
+	return b.String()
+}
+
+type completion struct {
+	at   time.Duration
+	done func(success bool)
+	fail bool
+}
+
+// completions is a min-heap of completions by time.
+type completions []completion
+
+func (c completions) Len() int           { return len(c) }
+func (c completions) Less(i, j int) bool { return c[i].at < c[j].at }
+func (c completions) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+func (c *completions) Push(x any)        { *c = append(*c, x.(completion)) }
+
+func (c *completions) Pop() any {
+	old := *c
+	x := old[len(old)-1]
+	*c = old[:len(old)-1]
+	return x
+}
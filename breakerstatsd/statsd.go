@@ -0,0 +1,133 @@
+// Package breakerstatsd emits circuit breaker metrics to StatsD or DogStatsD.
+package breakerstatsd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sj902/breaker"
+)
+
+// Emitter sends circuit breaker metrics over UDP. With plain StatsD the breaker
+// name is part of the metric name, e.g. "breaker.payments.failures"; with
+// DogStatsD it is sent as a "name" tag instead.
+type Emitter struct {
+	// Prefix is prepended to every metric name, "breaker." by default.
+	Prefix string
+	// DogStatsD enables tags and events.
+	DogStatsD bool
+	// Tags are added to every metric when DogStatsD is enabled.
+	Tags []string
+
+	conn net.Conn
+
+	mutex sync.Mutex
+	last  map[*breaker.CircuitBreaker]breaker.Metrics
+}
+
+// New returns an Emitter sending to the StatsD server at addr.
+func New(addr string) (*Emitter, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &Emitter{Prefix: "breaker.", conn: conn}, nil
+}
+
+// Close closes the connection to the server.
+func (e *Emitter) Close() error {
+	return e.conn.Close()
+}
+
+// Report emits the state of every circuit breaker as a gauge, 0 closed, 1
+// half-open and 2 open, and the requests, successes, failures and rejections
+// since the previous report as counters.
+func (e *Emitter) Report(cbs ...*breaker.CircuitBreaker) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.last == nil {
+		e.last = make(map[*breaker.CircuitBreaker]breaker.Metrics)
+	}
+
+	for _, cb := range cbs {
+		m := cb.Metrics()
+		prev := e.last[cb]
+		e.last[cb] = m
+
+		name := cb.Name()
+		e.send(name, "state", stateValue(cb.State()), "g")
+		e.send(name, "requests", m.Requests-prev.Requests, "c")
+		e.send(name, "successes", m.Successes-prev.Successes, "c")
+		e.send(name, "failures", m.Failures-prev.Failures, "c")
+		e.send(name, "rejections", m.Rejections-prev.Rejections, "c")
+	}
+}
+
+// Forget drops what the emitter remembers of cb since its last report. It
+// can be used as Registry.OnEvict so that evicted breakers don't pile up.
+func (e *Emitter) Forget(cb *breaker.CircuitBreaker) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	delete(e.last, cb)
+}
+
+// Run calls Report for cbs every interval until ctx is done.
+func (e *Emitter) Run(ctx context.Context, interval time.Duration, cbs ...*breaker.CircuitBreaker) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		e.Report(cbs...)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// OnStateChange emits a transitions counter, and an event with DogStatsD. It
+// can be used as Settings.OnStateChange.
+func (e *Emitter) OnStateChange(name string, from breaker.State, to breaker.State) {
+	e.send(name, "transitions", 1, "c", "to:"+to.String())
+	if !e.DogStatsD {
+		return
+	}
+
+	title := fmt.Sprintf("circuit breaker %s is %s", name, to)
+	text := fmt.Sprintf("%s went from %s to %s", name, from, to)
+	tags := append([]string{"name:" + name}, e.Tags...)
+	fmt.Fprintf(e.conn, "_e{%d,%d}:%s|%s|#%s", len(title), len(text), title, text, strings.Join(tags, ","))
+}
+
+func (e *Emitter) send(name, metric string, value interface{}, typ string, tags ...string) {
+	if !e.DogStatsD {
+		fmt.Fprintf(e.conn, "%s%s.%s:%v|%s", e.Prefix, sanitize(name), metric, value, typ)
+		return
+	}
+
+	tags = append(append([]string{"name:" + name}, tags...), e.Tags...)
+	fmt.Fprintf(e.conn, "%s%s:%v|%s|#%s", e.Prefix, metric, value, typ, strings.Join(tags, ","))
+}
+
+func stateValue(s breaker.State) int {
+	switch s {
+	case breaker.StateHalfOpen:
+		return 1
+	case breaker.StateOpen:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// sanitize replaces the characters of name with a meaning in the StatsD protocol.
+func sanitize(name string) string {
+	return strings.NewReplacer(":", "_", "|", "_", "@", "_", " ", "_").Replace(name)
+}
@@ -0,0 +1,12 @@
+module github.com/sj902/breaker/breakertwirp
+
+go 1.21
+
+require (
+	github.com/sj902/breaker v0.0.0
+	github.com/twitchtv/twirp v8.1.3+incompatible
+)
+
+require github.com/pkg/errors v0.9.1 // indirect
+
+replace github.com/sj902/breaker => ../
@@ -0,0 +1,105 @@
+// Package breakertwirp provides Twirp hooks running RPCs through circuit breakers.
+package breakertwirp
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/twitchtv/twirp"
+
+	"github.com/sj902/breaker"
+)
+
+// IsFailureCode is the default classifier of RPC errors: codes signalling an
+// unhealthy server count as failures, caller errors such as InvalidArgument or
+// NotFound count as successes.
+func IsFailureCode(code twirp.ErrorCode) bool {
+	switch code {
+	case twirp.Unknown, twirp.DeadlineExceeded, twirp.ResourceExhausted,
+		twirp.Internal, twirp.Unavailable, twirp.DataLoss, twirp.Malformed:
+		return true
+	default:
+		return false
+	}
+}
+
+// Rejection converts a rejection of cb into a twirp.Unavailable error, with the
+// breaker name and, while open, the remaining open duration as metadata.
+func Rejection(cb *breaker.CircuitBreaker, err error) twirp.Error {
+	twerr := twirp.NewError(twirp.Unavailable, err.Error()).WithMeta("breaker", cb.Name())
+	if d := cb.RetryAfter(); d > 0 {
+		twerr = twerr.WithMeta("retry_after", d.String())
+	}
+	return twerr
+}
+
+type callKey struct{}
+
+// call records the outcome of one RPC once.
+type call struct {
+	done   func(success bool)
+	once   sync.Once
+	failed bool
+}
+
+func (c *call) finish(success bool) {
+	c.once.Do(func() {
+		c.done(success)
+	})
+}
+
+func callFrom(ctx context.Context) *call {
+	c, _ := ctx.Value(callKey{}).(*call)
+	return c
+}
+
+// ClientHooks returns client hooks running every RPC through cb. Rejections are
+// returned as twirp.Unavailable errors, which generated clients wrap as internal
+// errors with the rejection as cause.
+func ClientHooks(cb *breaker.CircuitBreaker) *twirp.ClientHooks {
+	return &twirp.ClientHooks{
+		RequestPrepared: func(ctx context.Context, _ *http.Request) (context.Context, error) {
+			done, err := cb.Allow()
+			if err != nil {
+				return ctx, Rejection(cb, err)
+			}
+			return context.WithValue(ctx, callKey{}, &call{done: done}), nil
+		},
+		ResponseReceived: func(ctx context.Context) {
+			if c := callFrom(ctx); c != nil {
+				c.finish(true)
+			}
+		},
+		Error: func(ctx context.Context, twerr twirp.Error) {
+			if c := callFrom(ctx); c != nil {
+				c.finish(!IsFailureCode(twerr.Code()))
+			}
+		},
+	}
+}
+
+// ServerHooks returns server hooks running every RPC through cb. While cb
+// rejects requests, RPCs fail with twirp.Unavailable before reaching the handler.
+func ServerHooks(cb *breaker.CircuitBreaker) *twirp.ServerHooks {
+	return &twirp.ServerHooks{
+		RequestReceived: func(ctx context.Context) (context.Context, error) {
+			done, err := cb.Allow()
+			if err != nil {
+				return ctx, Rejection(cb, err)
+			}
+			return context.WithValue(ctx, callKey{}, &call{done: done}), nil
+		},
+		Error: func(ctx context.Context, twerr twirp.Error) context.Context {
+			if c := callFrom(ctx); c != nil {
+				c.failed = IsFailureCode(twerr.Code())
+			}
+			return ctx
+		},
+		ResponseSent: func(ctx context.Context) {
+			if c := callFrom(ctx); c != nil {
+				c.finish(!c.failed)
+			}
+		},
+	}
+}
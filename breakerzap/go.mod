@@ -0,0 +1,12 @@
+module github.com/sj902/breaker/breakerzap
+
+go 1.21
+
+require (
+	github.com/sj902/breaker v0.0.0
+	go.uber.org/zap v1.27.0
+)
+
+require go.uber.org/multierr v1.10.0 // indirect
+
+replace github.com/sj902/breaker => ../
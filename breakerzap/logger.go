@@ -0,0 +1,25 @@
+// Package breakerzap adapts zap loggers to breaker.Logger.
+package breakerzap
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/sj902/breaker"
+)
+
+type logger struct {
+	sugar *zap.SugaredLogger
+}
+
+// Logger returns a breaker.Logger logging to l.
+func Logger(l *zap.Logger) breaker.Logger {
+	return logger{sugar: l.Sugar()}
+}
+
+func (l logger) Info(msg string, args ...any) {
+	l.sugar.Infow(msg, args...)
+}
+
+func (l logger) Warn(msg string, args ...any) {
+	l.sugar.Warnw(msg, args...)
+}
@@ -0,0 +1,169 @@
+package breaker
+
+import (
+	"fmt"
+	"time"
+)
+
+// Builder builds a circuit breaker through chained calls:
+//
+//	cb, err := breaker.NewBuilder().Name("payments").FailureRate(0.5).MinRequests(20).OpenFor(30 * time.Second).Build()
+//
+// Every call checks its arguments; the first invalid one is returned by Build
+// and the calls after it are ignored.
+type Builder struct {
+	settings Settings
+	err      error
+}
+
+// NewBuilder returns a Builder starting from the default settings.
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// From starts the builder from st, e.g. from a preset.
+func (b *Builder) From(st Settings) *Builder {
+	return b.set(nil, func(s *Settings) { *s = st })
+}
+
+func (b *Builder) set(err error, f func(s *Settings)) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if err != nil {
+		b.err = err
+		return b
+	}
+	f(&b.settings)
+	return b
+}
+
+func positive[T int | time.Duration](what string, v T) error {
+	if v <= 0 {
+		return fmt.Errorf("%s must be positive, got %v", what, v)
+	}
+	return nil
+}
+
+func rate(what string, r float64) error {
+	if r <= 0 || r > 1 {
+		return fmt.Errorf("%s must be in (0, 1], got %v", what, r)
+	}
+	return nil
+}
+
+// Name sets the name of the circuit breaker.
+func (b *Builder) Name(name string) *Builder {
+	return b.set(nil, func(s *Settings) { s.Name = name })
+}
+
+// OpenFor sets how long the circuit stays open before half-opening.
+func (b *Builder) OpenFor(d time.Duration) *Builder {
+	return b.set(positive("open timeout", d), func(s *Settings) { s.OpenTimeout = d })
+}
+
+// Backoff sets the policy growing the open timeout on consecutive trips.
+func (b *Builder) Backoff(p BackoffPolicy) *Builder {
+	return b.set(nil, func(s *Settings) { s.BackoffPolicy = p })
+}
+
+// Interval sets how often the counts are cleared in the closed state.
+func (b *Builder) Interval(d time.Duration) *Builder {
+	return b.set(positive("interval", d), func(s *Settings) { s.Interval = d })
+}
+
+// FailureRate trips the circuit when the ratio of failures reaches r.
+func (b *Builder) FailureRate(r float64) *Builder {
+	return b.set(rate("failure rate", r), func(s *Settings) { s.FailureRateThreshold = r })
+}
+
+// MinRequests sets how many requests must complete before FailureRate applies.
+func (b *Builder) MinRequests(n int) *Builder {
+	return b.set(positive("minimum requests", n), func(s *Settings) { s.MinimumRequests = n })
+}
+
+// ConsecutiveFailures trips the circuit after n failures in a row.
+func (b *Builder) ConsecutiveFailures(n int) *Builder {
+	return b.set(positive("consecutive failures", n), func(s *Settings) {
+		s.ReadyToTrip = func(c Counts) bool { return c.ConsecutiveFail >= n }
+	})
+}
+
+// SlowCalls counts calls taking threshold or longer as slow and trips the
+// circuit when the ratio of slow calls reaches r.
+func (b *Builder) SlowCalls(threshold time.Duration, r float64) *Builder {
+	err := positive("slow call threshold", threshold)
+	if err == nil {
+		err = rate("slow call rate", r)
+	}
+	return b.set(err, func(s *Settings) {
+		s.SlowCallThreshold = threshold
+		s.SlowCallRateThreshold = r
+	})
+}
+
+// CountWindow counts the last n calls only.
+func (b *Builder) CountWindow(n int) *Builder {
+	return b.set(positive("window size", n), func(s *Settings) { s.WindowSize = n })
+}
+
+// TimeWindow counts the calls of the last d only, in buckets buckets.
+func (b *Builder) TimeWindow(d time.Duration, buckets int) *Builder {
+	err := positive("rolling window", d)
+	if err == nil {
+		err = positive("rolling buckets", buckets)
+	}
+	return b.set(err, func(s *Settings) {
+		s.RollingWindow = d
+		s.RollingBuckets = buckets
+	})
+}
+
+// HalfOpenRequests sets how many requests may probe the half-open circuit.
+func (b *Builder) HalfOpenRequests(n int) *Builder {
+	return b.set(positive("half-open requests", n), func(s *Settings) { s.MaxRequests = n })
+}
+
+// SuccessThreshold sets how many probes must succeed to close the circuit.
+func (b *Builder) SuccessThreshold(n int) *Builder {
+	return b.set(positive("success threshold", n), func(s *Settings) { s.SuccessThreshold = n })
+}
+
+// MaxConcurrent caps the requests in flight.
+func (b *Builder) MaxConcurrent(n int) *Builder {
+	return b.set(positive("max concurrent", n), func(s *Settings) { s.MaxConcurrent = n })
+}
+
+// Ignore sets errors that count neither as success nor as failure.
+func (b *Builder) Ignore(errs ...error) *Builder {
+	return b.set(nil, func(s *Settings) { s.IgnoredErrors = append(s.IgnoredErrors, errs...) })
+}
+
+// Classifier sets the function deciding the outcome of every error.
+func (b *Builder) Classifier(f func(err error) Outcome) *Builder {
+	return b.set(nil, func(s *Settings) { s.Classifier = f })
+}
+
+// Logger sets the logger of the circuit breaker.
+func (b *Builder) Logger(l Logger) *Builder {
+	return b.set(nil, func(s *Settings) { s.Logger = l })
+}
+
+// OnStateChange sets the function called on every state change.
+func (b *Builder) OnStateChange(f func(name string, from State, to State)) *Builder {
+	return b.set(nil, func(s *Settings) { s.OnStateChange = f })
+}
+
+// Settings returns the settings built so far and the first invalid call, if any.
+func (b *Builder) Settings() (Settings, error) {
+	return b.settings, b.err
+}
+
+// Build returns the circuit breaker, or the first invalid call or the
+// conflicts between the calls found by Settings.Validate.
+func (b *Builder) Build() (*CircuitBreaker, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	return New(b.settings)
+}
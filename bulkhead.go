@@ -0,0 +1,65 @@
+package breaker
+
+import (
+	"context"
+	"time"
+)
+
+const defaultMaxWait = time.Second
+
+// wait queues the request until a request in flight completes, timer fires or
+// ctx is done, in which cases it returns ErrQueueTimeout or ctx.Err(). Requests
+// are woken in arrival order; a woken request that has to wait again, front,
+// keeps its place at the head of the queue. It must be called with the mutex
+// held and releases it while waiting.
+func (cb *CircuitBreaker) wait(ctx context.Context, timer Timer, front bool) error {
+	ch := make(chan struct{})
+	if front {
+		cb.waiters = append([]chan struct{}{ch}, cb.waiters...)
+	} else {
+		cb.waiters = append(cb.waiters, ch)
+	}
+
+	cb.mutex.Unlock()
+	var err error
+	select {
+	case <-ch:
+	case <-timer.C():
+		err = ErrQueueTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	cb.mutex.Lock()
+
+	if err != nil && !cb.unqueue(ch) {
+		// Woken while giving up, so pass the slot on.
+		cb.wakeNext()
+	}
+	return err
+}
+
+// unqueue removes ch from the queue and reports whether it was still queued.
+func (cb *CircuitBreaker) unqueue(ch chan struct{}) bool {
+	for i, w := range cb.waiters {
+		if w == ch {
+			cb.waiters = append(cb.waiters[:i], cb.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// wakeNext wakes up the request at the head of the queue, if any.
+func (cb *CircuitBreaker) wakeNext() {
+	if len(cb.waiters) == 0 {
+		return
+	}
+	close(cb.waiters[0])
+	cb.waiters = cb.waiters[1:]
+}
+
+// release frees the slot of a request and hands it to the next queued request.
+func (cb *CircuitBreaker) release() {
+	cb.inFlight--
+	cb.wakeNext()
+}
@@ -0,0 +1,113 @@
+package breaker
+
+import (
+	"container/list"
+	"errors"
+	"sync"
+	"time"
+)
+
+const defaultCacheTTL = 5 * time.Minute
+const defaultCacheSize = 100
+
+// resultCache keeps the last successful result per key, evicting the least recently stored.
+type resultCache struct {
+	mutex   sync.Mutex
+	ttl     time.Duration
+	size    int
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type cacheEntry struct {
+	key    string
+	value  interface{}
+	stored time.Time
+}
+
+func newResultCache(ttl time.Duration, size int) *resultCache {
+	if ttl <= 0 {
+		ttl = defaultCacheTTL
+	}
+	if size <= 0 {
+		size = defaultCacheSize
+	}
+	return &resultCache{ttl: ttl, size: size}
+}
+
+func (c *resultCache) put(key string, value interface{}, t time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[string]*list.Element)
+		c.order = list.New()
+	}
+
+	if e, ok := c.entries[key]; ok {
+		e.Value = &cacheEntry{key: key, value: value, stored: t}
+		c.order.MoveToFront(e)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, value: value, stored: t})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// get returns the value stored for key and its age if it is younger than the ttl.
+func (c *resultCache) get(key string, t time.Time) (interface{}, time.Duration, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, 0, false
+	}
+
+	entry := e.Value.(*cacheEntry)
+	age := t.Sub(entry.stored)
+	if age > c.ttl {
+		c.order.Remove(e)
+		delete(c.entries, key)
+		return nil, 0, false
+	}
+	return entry.value, age, true
+}
+
+// ExecuteCached runs req like Execute and caches successful results under key.
+// While the circuit breaker rejects requests, the last cached value for key is
+// returned together with its age instead of the rejection error. Fresh results
+// have an age of zero.
+func (cb *CircuitBreaker) ExecuteCached(key string, req func() (interface{}, error)) (interface{}, time.Duration, error) {
+	return ExecuteCached(cb, key, req)
+}
+
+// ExecuteCached is the type safe variant of CircuitBreaker.ExecuteCached.
+func ExecuteCached[T any](cb *CircuitBreaker, key string, req func() (T, error)) (T, time.Duration, error) {
+	res, err := Execute(cb, req)
+	if err == nil {
+		cb.results().put(key, res, cb.clock.Now())
+		return res, 0, nil
+	}
+
+	if errors.Is(err, ErrOpenState) || errors.Is(err, ErrTooManyRequests) {
+		if v, age, ok := cb.results().get(key, cb.clock.Now()); ok {
+			if stale, ok := v.(T); ok {
+				return stale, age, nil
+			}
+		}
+	}
+	return res, 0, err
+}
+
+// results returns the result cache of cb, which UpdateSettings may replace.
+func (cb *CircuitBreaker) results() *resultCache {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	return cb.cache
+}
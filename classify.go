@@ -0,0 +1,125 @@
+package breaker
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Outcome is the classification of a completed request.
+type Outcome int
+
+const (
+	// OutcomeSuccess counts the request as a success.
+	OutcomeSuccess Outcome = iota
+	// OutcomeFailure counts the request as a failure.
+	OutcomeFailure
+	// OutcomeIgnored counts the request neither as a success nor as a failure,
+	// e.g. for context.Canceled or validation errors.
+	OutcomeIgnored
+	// OutcomeFatal counts the request as a failure and opens the circuit breaker immediately.
+	OutcomeFatal
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeSuccess:
+		return "success"
+	case OutcomeFailure:
+		return "failure"
+	case OutcomeIgnored:
+		return "ignored"
+	case OutcomeFatal:
+		return "fatal"
+	default:
+		return fmt.Sprintf("unknown outcome: %d", o)
+	}
+}
+
+// Classify returns the outcome cb records for a request that returned err,
+// as decided by Settings.Classifier and the ignored and fatal errors.
+func (cb *CircuitBreaker) Classify(err error) Outcome {
+	cb.mutex.Lock()
+	classify := cb.classifier
+	cb.mutex.Unlock()
+
+	return classify(err)
+}
+
+func outcomeOf(success bool) Outcome {
+	if success {
+		return OutcomeSuccess
+	}
+	return OutcomeFailure
+}
+
+func defaultClassifier(err error) Outcome {
+	return outcomeOf(err == nil)
+}
+
+// ErrorClass names a set of errors for ClassifyErrors. Errors match with the
+// rules of Settings.IgnoredErrors.
+type ErrorClass struct {
+	Name   string
+	Errors []error
+}
+
+// ClassifyErrors returns a Settings.ErrorClassifier naming an error after the
+// first class it matches, with the rules of Settings.IgnoredErrors, and
+// "other" if none matches.
+func ClassifyErrors(classes ...ErrorClass) func(err error) string {
+	return func(err error) string {
+		for _, class := range classes {
+			if matchesAny(err, class.Errors) {
+				return class.Name
+			}
+		}
+		return "other"
+	}
+}
+
+// matchesAny reports whether err matches one of targets with errors.Is or, when
+// the target is a zero value such as (*net.OpError)(nil), with errors.As on its type.
+func matchesAny(err error, targets []error) bool {
+	for _, target := range targets {
+		if errors.Is(err, target) {
+			return true
+		}
+		if isTypeTarget(target) && errors.As(err, reflect.New(reflect.TypeOf(target)).Interface()) {
+			return true
+		}
+	}
+	return false
+}
+
+func isTypeTarget(target error) bool {
+	v := reflect.ValueOf(target)
+	switch v.Kind() {
+	case reflect.Ptr:
+		return v.IsNil() || v.Elem().IsZero()
+	case reflect.Invalid:
+		return false
+	default:
+		return v.IsZero()
+	}
+}
+
+// ignoring wraps classify so that errors matching ignored are OutcomeIgnored.
+func ignoring(classify func(err error) Outcome, ignored []error) func(err error) Outcome {
+	return func(err error) Outcome {
+		if err != nil && matchesAny(err, ignored) {
+			return OutcomeIgnored
+		}
+		return classify(err)
+	}
+}
+
+// tripping wraps classify so that errors matching fatal, or for which trip returns true, are OutcomeFatal.
+func tripping(classify func(err error) Outcome, fatal []error, trip func(err error) bool) func(err error) Outcome {
+	return func(err error) Outcome {
+		if err != nil && (matchesAny(err, fatal) || trip != nil && trip(err)) {
+			return OutcomeFatal
+		}
+		return classify(err)
+	}
+}
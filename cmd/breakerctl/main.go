@@ -0,0 +1,153 @@
+// Command breakerctl inspects and controls circuit breakers through the
+// breakerhttp.Admin endpoint of a service.
+//
+// Usage:
+//
+//	breakerctl [-addr url] [-token token] list
+//	breakerctl [-addr url] [-token token] show <name>
+//	breakerctl [-addr url] [-token token] open|close|reset <name>
+//	breakerctl [-addr url] [-token token] watch [-interval 1s]
+//	breakerctl [-addr url] [-token token] top [-interval 1s]
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sj902/breaker"
+	"github.com/sj902/breaker/breakerhttp"
+)
+
+type client struct {
+	addr  string
+	token string
+}
+
+func main() {
+	addr := flag.String("addr", "http://localhost:8080/admin/breakers", "URL of the admin endpoint")
+	token := flag.String("token", os.Getenv("BREAKERCTL_TOKEN"), "bearer token sent to the admin endpoint")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: breakerctl [flags] list | show <name> | open <name> | close <name> | reset <name> | watch [-interval d] | top [-interval d]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	c := &client{addr: strings.TrimSuffix(*addr, "/"), token: *token}
+	if err := run(c, flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, "breakerctl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(c *client, args []string) error {
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	switch cmd := args[0]; cmd {
+	case "list":
+		statuses, err := c.list()
+		if err != nil {
+			return err
+		}
+		printTable(os.Stdout, statuses)
+		return nil
+	case "show", "open", "close", "reset":
+		if len(args) != 2 {
+			return fmt.Errorf("%s needs a breaker name", cmd)
+		}
+
+		method, path := http.MethodPost, "/"+cmd
+		if cmd == "show" {
+			method, path = http.MethodGet, "/breaker"
+		}
+		var status breakerhttp.BreakerStatus
+		if err := c.do(method, path+"?name="+url.QueryEscape(args[1]), &status); err != nil {
+			return err
+		}
+		printTable(os.Stdout, []breakerhttp.BreakerStatus{status})
+		return nil
+	case "watch", "top":
+		fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+		interval := fs.Duration("interval", time.Second, "polling interval")
+		fs.Parse(args[1:])
+		if cmd == "top" {
+			return c.top(*interval)
+		}
+		return c.watch(*interval)
+	default:
+		return fmt.Errorf("unknown command %q", cmd)
+	}
+}
+
+func (c *client) list() ([]breakerhttp.BreakerStatus, error) {
+	var statuses []breakerhttp.BreakerStatus
+	err := c.do(http.MethodGet, "/", &statuses)
+	return statuses, err
+}
+
+// watch polls the breakers every interval and prints their state changes.
+func (c *client) watch(interval time.Duration) error {
+	states := make(map[string]string)
+	for {
+		statuses, err := c.list()
+		if err != nil {
+			return err
+		}
+
+		for _, s := range statuses {
+			state := s.State.String()
+			if prev, ok := states[s.Name]; !ok || prev != state {
+				fmt.Printf("%s %s %s", time.Now().Format(time.RFC3339), s.Name, state)
+				if s.TripError != "" && s.State == breaker.StateOpen {
+					fmt.Printf(" (%s)", s.TripError)
+				}
+				fmt.Println()
+			}
+			states[s.Name] = state
+		}
+		time.Sleep(interval)
+	}
+}
+
+func (c *client) do(method, path string, v interface{}) error {
+	req, err := http.NewRequest(method, c.addr+path, nil)
+	if err != nil {
+		return err
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func printTable(w io.Writer, statuses []breakerhttp.BreakerStatus) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tSTATE\tREQUESTS\tFAILURES\tREJECTIONS\tFAILURE RATE\tRETRY AFTER\tLAST ERROR")
+	for _, s := range statuses {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%.1f%%\t%s\t%s\n",
+			s.Name, s.State, s.Metrics.Requests, s.Metrics.Failures, s.Metrics.Rejections,
+			s.Counts.FailureRate()*100, s.RetryAfter.Round(time.Second), s.LastError)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sj902/breaker"
+)
+
+const sparkHistory = 30
+
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+// topBreaker keeps the history of one breaker across refreshes.
+type topBreaker struct {
+	rates []float64
+	last  breaker.Metrics
+}
+
+// top redraws a live dashboard of the breakers every interval: their state,
+// failure rate, rejections per interval and a sparkline of the failure rate.
+func (c *client) top(interval time.Duration) error {
+	history := make(map[string]*topBreaker)
+	for {
+		statuses, err := c.list()
+		if err != nil {
+			return err
+		}
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "\x1b[H\x1b[2Jbreakerctl top - %s - %d breakers, every %s\n\n",
+			time.Now().Format("15:04:05"), len(statuses), interval)
+
+		tw := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tSTATE\tFAILURE RATE\tREQ/INT\tREJ/INT\tHISTORY\tLAST ERROR")
+		for _, s := range statuses {
+			h, ok := history[s.Name]
+			if !ok {
+				h = &topBreaker{last: s.Metrics}
+				history[s.Name] = h
+			}
+
+			rate := s.Counts.FailureRate()
+			h.rates = append(h.rates, rate)
+			if len(h.rates) > sparkHistory {
+				h.rates = h.rates[1:]
+			}
+
+			fmt.Fprintf(tw, "%s\t%s\t%.1f%%\t%d\t%d\t%s\t%s\n",
+				s.Name, colorState(s.State), rate*100,
+				s.Metrics.Requests-h.last.Requests, s.Metrics.Rejections-h.last.Rejections,
+				sparkline(h.rates), s.LastError)
+			h.last = s.Metrics
+		}
+		tw.Flush()
+
+		os.Stdout.WriteString(b.String())
+		time.Sleep(interval)
+	}
+}
+
+func colorState(s breaker.State) string {
+	switch s {
+	case breaker.StateOpen:
+		return "\x1b[31m" + s.String() + "\x1b[0m"
+	case breaker.StateHalfOpen:
+		return "\x1b[33m" + s.String() + "\x1b[0m"
+	default:
+		return "\x1b[32m" + s.String() + "\x1b[0m"
+	}
+}
+
+// sparkline renders rates between 0 and 1 as block characters.
+func sparkline(rates []float64) string {
+	r := make([]rune, len(rates))
+	for i, rate := range rates {
+		level := int(rate * float64(len(sparks)-1))
+		if level < 0 {
+			level = 0
+		}
+		if level >= len(sparks) {
+			level = len(sparks) - 1
+		}
+		r[i] = sparks[level]
+	}
+	return string(r)
+}
@@ -0,0 +1,108 @@
+package breaker
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const defaultEventBuffer = 100
+
+// EventType identifies the kind of an Event.
+type EventType int
+
+const (
+	// EventTransition is sent on every state change.
+	EventTransition EventType = iota
+	// EventTrip is sent, after its EventTransition, when the circuit opens.
+	EventTrip
+	// EventRejection is sent when a request is rejected.
+	EventRejection
+	// EventProbe is sent when a request admitted in the half-open state completes.
+	EventProbe
+	// EventSettingsChange is sent when UpdateSettings retunes the circuit breaker.
+	EventSettingsChange
+)
+
+// String implements stringer interface.
+func (t EventType) String() string {
+	switch t {
+	case EventTransition:
+		return "transition"
+	case EventTrip:
+		return "trip"
+	case EventRejection:
+		return "rejection"
+	case EventProbe:
+		return "probe"
+	case EventSettingsChange:
+		return "settings change"
+	default:
+		return fmt.Sprintf("unknown event type: %d", t)
+	}
+}
+
+// Event describes something that happened to a circuit breaker.
+type Event struct {
+	Type EventType
+	Name string
+	Time time.Time
+	// From and To are the states of an EventTransition.
+	From State
+	To   State
+	// Counts holds the counts that led to an EventTransition or EventTrip.
+	Counts Counts
+	// Err is the error of an EventRejection.
+	Err error
+	// Success is the outcome of an EventProbe.
+	Success bool
+}
+
+// Events returns a new channel receiving the events of the circuit breaker
+// from now on, so that several consumers can subscribe independently. Events
+// are dropped, counted in Metrics.DroppedEvents, when the buffer of
+// Settings.EventBuffer events of a channel is full.
+// The channel stays subscribed for the life of the circuit breaker, see
+// EventsContext to unsubscribe.
+func (cb *CircuitBreaker) Events() <-chan Event {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	ch := make(chan Event, cb.eventBuffer)
+	cb.events = append(cb.events, ch)
+	return ch
+}
+
+// EventsContext is like Events but unsubscribes and closes the channel once
+// ctx is done.
+func (cb *CircuitBreaker) EventsContext(ctx context.Context) <-chan Event {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	ch := make(chan Event, cb.eventBuffer)
+	cb.events = append(cb.events, ch)
+	context.AfterFunc(ctx, func() {
+		cb.mutex.Lock()
+		defer cb.mutex.Unlock()
+
+		for i, c := range cb.events {
+			if c == ch {
+				cb.events = append(cb.events[:i], cb.events[i+1:]...)
+				break
+			}
+		}
+		close(ch)
+	})
+	return ch
+}
+
+func (cb *CircuitBreaker) emit(e Event) {
+	e.Name = cb.name
+	for _, ch := range cb.events {
+		select {
+		case ch <- e:
+		default:
+			cb.metrics.DroppedEvents++
+		}
+	}
+}
@@ -13,34 +13,22 @@ var cb *breaker.CircuitBreaker
 
 func init() {
 	var st breaker.Settings
-	st.ReadyToTrip = func(counts breaker.Counts) bool {
-		failureRatio := float64(counts.TotalFail) / float64(counts.Requests)
-		return counts.Requests >= 3 && failureRatio >= 0.5
-	}
+	st.FailureRateThreshold = 0.5
+	st.MinimumRequests = 3
 
 	cb = breaker.NewCircuitBreaker(st)
 }
 
 func Get(url string) ([]byte, error) {
-	body, err := cb.Execute(func() (interface{}, error) {
+	return breaker.Execute(cb, func() ([]byte, error) {
 		resp, err := http.Get(url)
 		if err != nil {
 			return nil, err
 		}
 
 		defer resp.Body.Close()
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return nil, err
-		}
-
-		return body, nil
+		return io.ReadAll(resp.Body)
 	})
-	if err != nil {
-		return nil, err
-	}
-
-	return body.([]byte), nil
 }
 
 func main() {
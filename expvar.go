@@ -0,0 +1,55 @@
+package breaker
+
+import (
+	"encoding/json"
+	"expvar"
+	"sync"
+)
+
+var (
+	expvarMutex sync.Mutex
+	expvarMap   *expvar.Map
+)
+
+// expvarBreaker is the expvar.Var of a published circuit breaker.
+type expvarBreaker struct {
+	cb *CircuitBreaker
+}
+
+func (v expvarBreaker) String() string {
+	data, _ := json.Marshal(map[string]interface{}{
+		"state":   v.cb.State().String(),
+		"counts":  v.cb.Counts(),
+		"metrics": v.cb.Metrics(),
+	})
+	return string(data)
+}
+
+// PublishExpvar publishes the live state, counts and metrics of the circuit
+// breaker under the "breaker" expvar map, keyed by its name, so that they show
+// up at /debug/vars as breaker.<name>.state and so on. Publishing another
+// circuit breaker with the same name replaces it.
+func (cb *CircuitBreaker) PublishExpvar() {
+	expvarMutex.Lock()
+	defer expvarMutex.Unlock()
+
+	if expvarMap == nil {
+		expvarMap = expvar.NewMap("breaker")
+	}
+	expvarMap.Set(cb.name, expvarBreaker{cb: cb})
+}
+
+// UnpublishExpvar removes the circuit breaker from the "breaker" expvar map,
+// unless another one was published under its name since. A Registry calls it
+// for the circuit breakers it evicts or removes.
+func (cb *CircuitBreaker) UnpublishExpvar() {
+	expvarMutex.Lock()
+	defer expvarMutex.Unlock()
+
+	if expvarMap == nil {
+		return
+	}
+	if v, ok := expvarMap.Get(cb.name).(expvarBreaker); ok && v.cb == cb {
+		expvarMap.Delete(cb.name)
+	}
+}
@@ -0,0 +1,47 @@
+package breaker
+
+// ExecuteWithFallback runs req like Execute and calls fallback with the error
+// when the request is rejected or fails. The result of fallback is returned instead.
+func (cb *CircuitBreaker) ExecuteWithFallback(req func() (interface{}, error), fallback func(err error) (interface{}, error)) (interface{}, error) {
+	return ExecuteWithFallback(cb, req, fallback)
+}
+
+// ExecuteWithFallback is the type safe variant of CircuitBreaker.ExecuteWithFallback.
+func ExecuteWithFallback[T any](cb *CircuitBreaker, req func() (T, error), fallback func(err error) (T, error)) (T, error) {
+	res, err := Execute(cb, req)
+	if err != nil {
+		return fallback(err)
+	}
+	return res, nil
+}
+
+// Fallback is one step of a fallback chain. Req runs through Breaker,
+// or directly when Breaker is nil, e.g. for a static default.
+type Fallback[T any] struct {
+	Breaker *CircuitBreaker
+	Req     func() (T, error)
+}
+
+// ExecuteChain runs req like Execute and, when it is rejected or fails, tries the
+// fallbacks in order. Each fallback is recorded by its own breaker. The first
+// successful result is returned, otherwise the error of the last fallback.
+func (cb *CircuitBreaker) ExecuteChain(req func() (interface{}, error), fallbacks ...Fallback[interface{}]) (interface{}, error) {
+	return ExecuteChain(cb, req, fallbacks...)
+}
+
+// ExecuteChain is the type safe variant of CircuitBreaker.ExecuteChain.
+func ExecuteChain[T any](cb *CircuitBreaker, req func() (T, error), fallbacks ...Fallback[T]) (T, error) {
+	res, err := Execute(cb, req)
+	for _, fb := range fallbacks {
+		if err == nil {
+			break
+		}
+
+		if fb.Breaker == nil {
+			res, err = fb.Req()
+		} else {
+			res, err = Execute(fb.Breaker, fb.Req)
+		}
+	}
+	return res, err
+}
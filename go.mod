@@ -1,3 +1,3 @@
 module github.com/sj902/breaker
 
-go 1.19
+go 1.21
@@ -0,0 +1,79 @@
+package breaker
+
+import (
+	"sort"
+)
+
+const healthWorstOffenders = 5
+
+// HealthReport summarizes the circuit breakers of a Registry.
+type HealthReport struct {
+	Total    int `json:"total"`
+	Closed   int `json:"closed"`
+	HalfOpen int `json:"halfOpen"`
+	Open     int `json:"open"`
+	// Worst lists up to five breakers with the highest rejection rate, among
+	// the ones that rejected requests, highest first.
+	Worst []BreakerHealth `json:"worst,omitempty"`
+}
+
+// BreakerHealth describes one circuit breaker of a HealthReport.
+type BreakerHealth struct {
+	Name  string `json:"name"`
+	State State  `json:"state"`
+	// RejectionRate is the lifetime ratio of rejected to attempted requests.
+	RejectionRate float64 `json:"rejectionRate"`
+	// FailureRate is the failure rate of the current generation.
+	FailureRate float64 `json:"failureRate"`
+	LastError   string  `json:"lastError,omitempty"`
+}
+
+// Healthy reports whether no circuit breaker is open.
+func (h HealthReport) Healthy() bool {
+	return h.Open == 0
+}
+
+// Health returns a summary of the circuit breakers of the registry, e.g. for
+// a service health endpoint.
+func (r *Registry) Health() HealthReport {
+	var report HealthReport
+	var offenders []BreakerHealth
+
+	for _, cb := range r.Breakers() {
+		state := cb.State()
+		report.Total++
+		switch state {
+		case StateClosed:
+			report.Closed++
+		case StateHalfOpen:
+			report.HalfOpen++
+		case StateOpen:
+			report.Open++
+		}
+
+		m := cb.Metrics()
+		if m.Rejections == 0 {
+			continue
+		}
+
+		h := BreakerHealth{
+			Name:          cb.Name(),
+			State:         state,
+			RejectionRate: float64(m.Rejections) / float64(m.Requests+m.Rejections),
+			FailureRate:   cb.Counts().FailureRate(),
+		}
+		if err := cb.LastError(); err != nil {
+			h.LastError = err.Error()
+		}
+		offenders = append(offenders, h)
+	}
+
+	sort.SliceStable(offenders, func(i, j int) bool {
+		return offenders[i].RejectionRate > offenders[j].RejectionRate
+	})
+	if len(offenders) > healthWorstOffenders {
+		offenders = offenders[:healthWorstOffenders]
+	}
+	report.Worst = offenders
+	return report
+}
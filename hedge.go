@@ -0,0 +1,63 @@
+package breaker
+
+import (
+	"context"
+	"time"
+)
+
+// ExecuteHedged runs req like ExecuteContext and, if it has not completed after delay,
+// starts a second attempt. The first successful result wins and the other attempt's
+// context is canceled. The request is admitted and recorded once.
+func (cb *CircuitBreaker) ExecuteHedged(ctx context.Context, delay time.Duration, req func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	return ExecuteHedged(ctx, cb, delay, req)
+}
+
+// ExecuteHedged is the type safe variant of CircuitBreaker.ExecuteHedged.
+func ExecuteHedged[T any](ctx context.Context, cb *CircuitBreaker, delay time.Duration, req func(ctx context.Context) (T, error)) (T, error) {
+	if err := ctx.Err(); err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return execute(ctx, cb, func() (T, error) {
+		return hedge(ctx, cb.clock, delay, req)
+	})
+}
+
+type attempt[T any] struct {
+	res T
+	err error
+}
+
+func hedge[T any](ctx context.Context, clock Clock, delay time.Duration, req func(ctx context.Context) (T, error)) (T, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan attempt[T], 2)
+	run := func() {
+		res, err := req(ctx)
+		results <- attempt[T]{res: res, err: err}
+	}
+
+	go run()
+	running := 1
+
+	timer := clock.NewTimer(delay)
+	defer timer.Stop()
+
+	for {
+		select {
+		case a := <-results:
+			running--
+			if a.err == nil || running == 0 {
+				return a.res, a.err
+			}
+		case <-timer.C():
+			running++
+			go run()
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package breaker
+
+import "time"
+
+// Inspection is the internal state of a circuit breaker, exposed to check the
+// invariants of its state machine in property tests, see the breakercheck
+// package.
+type Inspection struct {
+	State      State
+	Generation int
+	Counts     Counts
+	// Expiry is when the current generation ends, zero in the half-open state.
+	Expiry      time.Time
+	Trips       int
+	Probes      int
+	InFlight    int
+	MaxRequests int
+	Overridden  bool
+}
+
+// Inspect returns the internal state of the circuit breaker as it is, without
+// applying the transitions due by now as State does.
+func (cb *CircuitBreaker) Inspect() Inspection {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	return Inspection{
+		State:       cb.state,
+		Generation:  cb.generation,
+		Counts:      cb.snapshot(cb.clock.Now()),
+		Expiry:      cb.expiry,
+		Trips:       cb.trips,
+		Probes:      cb.probes,
+		InFlight:    cb.inFlight,
+		MaxRequests: cb.maxRequests,
+		Overridden:  cb.override != overrideNone,
+	}
+}
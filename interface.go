@@ -0,0 +1,18 @@
+package breaker
+
+import "context"
+
+// Breaker is the part of CircuitBreaker used by code running requests, so
+// that it can depend on an interface and be tested with the fake of the
+// breakermock package.
+type Breaker interface {
+	Name() string
+	State() State
+	Counts() Counts
+	Execute(req func() (interface{}, error)) (interface{}, error)
+	ExecuteContext(ctx context.Context, req func(ctx context.Context) (interface{}, error)) (interface{}, error)
+	Do(req func() error) error
+	Allow() (done func(success bool), err error)
+}
+
+var _ Breaker = &CircuitBreaker{}
@@ -0,0 +1,82 @@
+package breaker
+
+import (
+	"time"
+)
+
+// Logger is the interface the circuit breaker logs through, with key-value
+// pairs following msg. *slog.Logger implements it; the breakerzap and
+// breakerlogrus modules adapt zap and logrus loggers.
+type Logger interface {
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+}
+
+// rejectionLogInterval is the minimum time between two logged rejections,
+// the ones in between are only counted.
+const rejectionLogInterval = time.Second
+
+func (cb *CircuitBreaker) logTransition(from State, to State, c Counts) {
+	if cb.logger == nil {
+		return
+	}
+
+	args := []any{
+		"name", cb.name,
+		"from", from.String(),
+		"to", to.String(),
+		"requests", c.Requests,
+		"successes", c.TotalSuccess,
+		"failures", c.TotalFail,
+		"consecutive_failures", c.ConsecutiveFail,
+	}
+	if to == StateOpen {
+		cb.logger.Warn("circuit breaker opened", args...)
+	} else {
+		cb.logger.Info("circuit breaker state changed", args...)
+	}
+}
+
+func (cb *CircuitBreaker) logSettings() {
+	if cb.logger == nil {
+		return
+	}
+
+	cb.logger.Info("circuit breaker settings updated",
+		"name", cb.name,
+		"open_timeout", cb.openTimeout,
+		"interval", cb.interval,
+		"max_requests", cb.maxRequests,
+		"success_threshold", cb.successThreshold,
+		"failure_rate_threshold", cb.settings.FailureRateThreshold,
+		"minimum_requests", cb.minimumRequests,
+		"max_concurrent", cb.maxConcurrent,
+		"disabled", cb.disabled,
+	)
+}
+
+func (cb *CircuitBreaker) logStoreError(op string, err error) {
+	if cb.logger == nil {
+		return
+	}
+	cb.logger.Warn("circuit breaker store failed", "name", cb.name, "op", op, "error", err)
+}
+
+func (cb *CircuitBreaker) logRejection(err error, t time.Time) {
+	if cb.logger == nil {
+		return
+	}
+	if t.Sub(cb.lastRejectionLog) < rejectionLogInterval {
+		cb.suppressedRejections++
+		return
+	}
+
+	cb.logger.Info("circuit breaker rejected a request",
+		"name", cb.name,
+		"state", cb.state.String(),
+		"error", err,
+		"suppressed", cb.suppressedRejections,
+	)
+	cb.lastRejectionLog = t
+	cb.suppressedRejections = 0
+}
@@ -0,0 +1,36 @@
+package breaker
+
+// Metrics holds the lifetime totals of a circuit breaker. Unlike Counts they
+// are never cleared, which makes them suitable for monitoring counters.
+type Metrics struct {
+	Requests   uint64
+	Successes  uint64
+	Failures   uint64
+	Rejections uint64
+	// Transitions counts the transitions into each state.
+	Transitions map[State]uint64
+	// DroppedEvents counts the events dropped because the Events buffer was full.
+	DroppedEvents uint64
+}
+
+// Metrics returns a snapshot of the lifetime totals of the circuit breaker.
+func (cb *CircuitBreaker) Metrics() Metrics {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	m := cb.metrics
+	m.Transitions = make(map[State]uint64, len(cb.metrics.Transitions))
+	for s, n := range cb.metrics.Transitions {
+		m.Transitions[s] = n
+	}
+	return m
+}
+
+func (m *Metrics) onOutcome(outcome Outcome) {
+	switch outcome {
+	case OutcomeSuccess:
+		m.Successes++
+	case OutcomeFailure, OutcomeFatal:
+		m.Failures++
+	}
+}
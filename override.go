@@ -0,0 +1,99 @@
+package breaker
+
+import "time"
+
+type override int
+
+const (
+	overrideNone override = iota
+	overrideOpen
+	overrideClosed
+)
+
+// ForceOpen pins the circuit breaker in the open state until ClearOverride is called.
+func (cb *CircuitBreaker) ForceOpen() {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	cb.override = overrideOpen
+}
+
+// ForceClosed pins the circuit breaker in the closed state until ClearOverride is called.
+// Outcomes are still counted but never trip the breaker.
+func (cb *CircuitBreaker) ForceClosed() {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	cb.override = overrideClosed
+}
+
+// ClearOverride removes ForceOpen or ForceClosed and resumes in the closed
+// state with cleared counts, so that outcomes counted while forced don't trip it.
+func (cb *CircuitBreaker) ClearOverride() {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	if cb.override == overrideNone {
+		return
+	}
+
+	cb.override = overrideNone
+	cb.restart(cb.clock.Now())
+}
+
+// SetDisabled turns the circuit breaker into a pass-through that never rejects requests.
+// Outcomes are still counted and the state keeps changing as usual.
+func (cb *CircuitBreaker) SetDisabled(disabled bool) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	cb.disabled = disabled
+}
+
+// Reset removes any override and restarts the circuit breaker in the closed
+// state with cleared counts.
+func (cb *CircuitBreaker) Reset() {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	cb.override = overrideNone
+	cb.restart(cb.clock.Now())
+}
+
+// restart switches to the closed state with cleared counts. It runs with the breaker locked.
+func (cb *CircuitBreaker) restart(t time.Time) {
+	if cb.state != StateClosed {
+		cb.setState(StateClosed, t)
+		return
+	}
+
+	if cb.window != nil {
+		cb.window.clear()
+	}
+	cb.newGeneration(t)
+}
+
+// Trip opens the circuit as if its counts had tripped it, e.g. when another
+// instance reports the dependency down. It half-opens after the timeout as
+// usual. Trip is a no-op while an override is active.
+func (cb *CircuitBreaker) Trip() {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	now := cb.clock.Now()
+	cb.currentState(now)
+	cb.setState(StateOpen, now)
+}
+
+// Recover closes the circuit with cleared counts, undoing a Trip once the
+// instance that reported the dependency down sees it up again. Unlike Reset
+// it keeps any override: Recover is a no-op while one is active.
+func (cb *CircuitBreaker) Recover() {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	if cb.override != overrideNone {
+		return
+	}
+	cb.restart(cb.clock.Now())
+}
@@ -0,0 +1,24 @@
+package breaker
+
+import "fmt"
+
+// PanicPolicy controls how a panic in a request affects the circuit breaker.
+type PanicPolicy int
+
+const (
+	// PanicCountAsFailure records the panic as a failure and panics again.
+	PanicCountAsFailure PanicPolicy = iota
+	// PanicIgnore records nothing and panics again.
+	PanicIgnore
+	// PanicConvertToError records the panic as a failure and returns it as a *PanicError.
+	PanicConvertToError
+)
+
+// PanicError is returned by Execute for a recovered panic under PanicConvertToError.
+type PanicError struct {
+	Value interface{}
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
@@ -0,0 +1,59 @@
+package breaker
+
+import "time"
+
+// The presets return settings tuned for common dependencies. They are a
+// starting point: set Name and anything specific to the dependency on the
+// result, or pass it to Builder.From.
+
+// PresetHTTPClient suits calls to HTTP services: it trips when half of the
+// calls of the last 30s fail or 80% take over 5s, once 20 calls completed,
+// and probes again after 30s, backing off up to 5m on repeated trips.
+func PresetHTTPClient() Settings {
+	return Settings{
+		OpenTimeout:           30 * time.Second,
+		BackoffPolicy:         ExponentialBackoff{Initial: 30 * time.Second, Max: 5 * time.Minute},
+		RollingWindow:         30 * time.Second,
+		RollingBuckets:        10,
+		FailureRateThreshold:  0.5,
+		MinimumRequests:       20,
+		SlowCallThreshold:     5 * time.Second,
+		SlowCallRateThreshold: 0.8,
+		MaxRequests:           5,
+		SuccessThreshold:      3,
+	}
+}
+
+// PresetDatabase suits database calls, which fail less often and recover
+// faster: it trips when 30% of the calls of the last minute fail or half take
+// over 1s, once 10 calls completed, and probes again after 10s with a single
+// request at a time.
+func PresetDatabase() Settings {
+	return Settings{
+		OpenTimeout:           10 * time.Second,
+		BackoffPolicy:         ExponentialBackoff{Initial: 10 * time.Second, Max: time.Minute},
+		RollingWindow:         time.Minute,
+		RollingBuckets:        12,
+		FailureRateThreshold:  0.3,
+		MinimumRequests:       10,
+		SlowCallThreshold:     time.Second,
+		SlowCallRateThreshold: 0.5,
+		MaxRequests:           3,
+		SingleProbe:           true,
+	}
+}
+
+// PresetAggressive protects fragile dependencies at the cost of false trips:
+// it trips when 20% of the last 20 calls fail, once 5 completed, and stays
+// open for a minute, backing off up to 10m on repeated trips.
+func PresetAggressive() Settings {
+	return Settings{
+		OpenTimeout:          time.Minute,
+		BackoffPolicy:        ExponentialBackoff{Initial: time.Minute, Max: 10 * time.Minute},
+		WindowSize:           20,
+		FailureRateThreshold: 0.2,
+		MinimumRequests:      5,
+		MaxRequests:          1,
+		SingleProbe:          true,
+	}
+}
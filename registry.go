@@ -0,0 +1,204 @@
+package breaker
+
+import (
+	"container/list"
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Registry creates and stores named circuit breakers sharing default settings.
+// It is safe for concurrent use.
+type Registry struct {
+	defaults Settings
+	maxSize  int
+
+	mutex    sync.Mutex
+	breakers map[string]*list.Element
+	order    *list.List
+	onEvict  func(cb *CircuitBreaker)
+}
+
+// NewRegistry returns a Registry creating its circuit breakers from defaults.
+func NewRegistry(defaults Settings) *Registry {
+	return NewBoundedRegistry(defaults, 0)
+}
+
+// NewBoundedRegistry returns a Registry keeping at most maxSize circuit
+// breakers, evicting the least recently used one beyond that. It suits high
+// cardinality keys, such as per customer or per URL breakers. A maxSize of
+// zero means no limit.
+func NewBoundedRegistry(defaults Settings, maxSize int) *Registry {
+	return &Registry{
+		defaults: defaults,
+		maxSize:  maxSize,
+		breakers: make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Get returns the circuit breaker named name, creating it on first use.
+func (r *Registry) Get(name string) *CircuitBreaker {
+	r.mutex.Lock()
+	if e, ok := r.breakers[name]; ok {
+		r.order.MoveToFront(e)
+		r.mutex.Unlock()
+		return e.Value.(*CircuitBreaker)
+	}
+
+	st := r.defaults
+	st.Name = name
+	cb := NewCircuitBreaker(st)
+	evicted := r.add(cb)
+	onEvict := r.onEvict
+	r.mutex.Unlock()
+
+	if evicted != nil && onEvict != nil {
+		onEvict(evicted)
+	}
+	return cb
+}
+
+// SetDefaults sets the settings of the circuit breakers created from now on.
+func (r *Registry) SetDefaults(defaults Settings) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.defaults = defaults
+}
+
+// Lookup returns the circuit breaker named name, if any, without creating it.
+func (r *Registry) Lookup(name string) (*CircuitBreaker, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	e, ok := r.breakers[name]
+	if !ok {
+		return nil, false
+	}
+	return e.Value.(*CircuitBreaker), true
+}
+
+// Add stores cb under its name, replacing the circuit breaker of the same
+// name, e.g. to give one breaker settings of its own.
+func (r *Registry) Add(cb *CircuitBreaker) {
+	r.mutex.Lock()
+	r.remove(cb.name)
+	evicted := r.add(cb)
+	onEvict := r.onEvict
+	r.mutex.Unlock()
+
+	if evicted != nil && onEvict != nil {
+		onEvict(evicted)
+	}
+}
+
+// Remove removes the circuit breaker named name.
+func (r *Registry) Remove(name string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.remove(name)
+}
+
+// Len returns the number of circuit breakers in the registry.
+func (r *Registry) Len() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.order.Len()
+}
+
+// Breakers returns the circuit breakers of the registry sorted by name.
+func (r *Registry) Breakers() []*CircuitBreaker {
+	r.mutex.Lock()
+	cbs := make([]*CircuitBreaker, 0, r.order.Len())
+	for e := r.order.Front(); e != nil; e = e.Next() {
+		cbs = append(cbs, e.Value.(*CircuitBreaker))
+	}
+	r.mutex.Unlock()
+
+	sort.Slice(cbs, func(i, j int) bool {
+		return cbs[i].name < cbs[j].name
+	})
+	return cbs
+}
+
+// OnEvict sets a function called with every circuit breaker evicted by the
+// size limit or expired by ExpireIdle, e.g. to unregister its metrics.
+func (r *Registry) OnEvict(f func(cb *CircuitBreaker)) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.onEvict = f
+}
+
+// ExpireIdle removes the circuit breakers that have had no request in flight
+// and received none for ttl, and returns them.
+func (r *Registry) ExpireIdle(ttl time.Duration) []*CircuitBreaker {
+	r.mutex.Lock()
+	var expired []*CircuitBreaker
+	for e := r.order.Front(); e != nil; {
+		next := e.Next()
+		if cb := e.Value.(*CircuitBreaker); cb.idle(cb.clock.Now(), ttl) {
+			r.remove(cb.name)
+			expired = append(expired, cb)
+		}
+		e = next
+	}
+	onEvict := r.onEvict
+	r.mutex.Unlock()
+
+	if onEvict != nil {
+		for _, cb := range expired {
+			onEvict(cb)
+		}
+	}
+	return expired
+}
+
+// RunExpiry calls ExpireIdle with ttl every ttl/2 until ctx is done. It
+// returns right away if ttl is not positive.
+func (r *Registry) RunExpiry(ctx context.Context, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	interval := ttl / 2
+	if interval <= 0 {
+		interval = ttl
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.ExpireIdle(ttl)
+		}
+	}
+}
+
+// add stores cb and returns the circuit breaker evicted to make room for it, if any.
+func (r *Registry) add(cb *CircuitBreaker) *CircuitBreaker {
+	r.breakers[cb.name] = r.order.PushFront(cb)
+	if r.maxSize > 0 && r.order.Len() > r.maxSize {
+		evicted := r.order.Back().Value.(*CircuitBreaker)
+		r.remove(evicted.name)
+		return evicted
+	}
+	return nil
+}
+
+func (r *Registry) remove(name string) {
+	e, ok := r.breakers[name]
+	if !ok {
+		return
+	}
+	r.order.Remove(e)
+	delete(r.breakers, name)
+	e.Value.(*CircuitBreaker).UnpublishExpvar()
+}
@@ -0,0 +1,132 @@
+package breaker
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RetryPolicy retries failed requests within a single admission, so that a
+// request is only recorded as a failure once its retries are exhausted.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	MaxAttempts int
+	// Backoff is the delay before each retry, no delay if nil.
+	Backoff BackoffPolicy
+	// Retryable reports whether err is worth retrying, all errors are if nil.
+	Retryable func(err error) bool
+	// Budget limits the retries relative to the requests of the breaker, unlimited if nil.
+	Budget *RetryBudget
+}
+
+// RetryBudget allows retries as long as they stay under Ratio of the requests
+// seen during the last Window. MinRetries are always allowed per Window so that
+// low traffic can still retry.
+type RetryBudget struct {
+	Ratio      float64
+	MinRetries int
+	Window     time.Duration
+}
+
+const defaultRetryBudgetRatio = 0.1
+const defaultRetryBudgetMinRetries = 10
+const defaultRetryBudgetWindow = 10 * time.Second
+
+// retryBudgetError is returned when a retry was denied by the budget. It wraps
+// the error of the last attempt and matches ErrRetryBudgetExhausted.
+type retryBudgetError struct {
+	err error
+}
+
+func (e *retryBudgetError) Error() string {
+	return ErrRetryBudgetExhausted.Error() + ": " + e.err.Error()
+}
+
+func (e *retryBudgetError) Unwrap() error {
+	return e.err
+}
+
+func (e *retryBudgetError) Is(target error) bool {
+	return target == ErrRetryBudgetExhausted
+}
+
+// retryBudget tracks the requests and retries of one breaker. The window records
+// requests as successes and retries as failures.
+type retryBudget struct {
+	mutex      sync.Mutex
+	ratio      float64
+	minRetries int
+	window     *timeWindow
+}
+
+func newRetryBudget(b *RetryBudget) *retryBudget {
+	rb := &retryBudget{ratio: b.Ratio, minRetries: b.MinRetries}
+	if rb.ratio <= 0 {
+		rb.ratio = defaultRetryBudgetRatio
+	}
+	if rb.minRetries <= 0 {
+		rb.minRetries = defaultRetryBudgetMinRetries
+	}
+
+	window := b.Window
+	if window <= 0 {
+		window = defaultRetryBudgetWindow
+	}
+	rb.window = newTimeWindow(window, defaultRollingBuckets)
+	return rb
+}
+
+func (b *retryBudget) onRequest(t time.Time) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.window.record(true, false, t)
+}
+
+// withdraw records a retry and reports whether the budget allowed it.
+func (b *retryBudget) withdraw(t time.Time) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	var c Counts
+	b.window.apply(&c, t)
+	if c.TotalFail >= b.minRetries && float64(c.TotalFail+1) > b.ratio*float64(c.TotalSuccess) {
+		return false
+	}
+
+	b.window.record(false, false, t)
+	return true
+}
+
+func (p *RetryPolicy) retryable(err error) bool {
+	return p.Retryable == nil || p.Retryable(err)
+}
+
+// call runs req, retrying it according to the retry policy of a if any. Once
+// ctx is done no retry is made and the result of the last attempt is returned.
+func call[T any](ctx context.Context, cb *CircuitBreaker, a admission, req func() (T, error)) (T, error) {
+	p := a.retryPolicy
+	if a.retryBudget != nil {
+		a.retryBudget.onRequest(cb.clock.Now())
+	}
+
+	res, err := req()
+	if p == nil {
+		return res, err
+	}
+
+	for attempt := 1; err != nil && attempt < p.MaxAttempts && p.retryable(err); attempt++ {
+		if a.retryBudget != nil && !a.retryBudget.withdraw(cb.clock.Now()) {
+			return res, &retryBudgetError{err: err}
+		}
+		var backoff time.Duration
+		if p.Backoff != nil {
+			backoff = p.Backoff.Backoff(attempt)
+		}
+		if !sleep(ctx, cb.clock, backoff) {
+			return res, err
+		}
+		res, err = req()
+	}
+	return res, err
+}
@@ -0,0 +1,256 @@
+package breaker
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const defaultSyncInterval = time.Second
+
+// SharedState shares the state and counts of circuit breakers between the
+// instances of a service, see Settings.SharedState. It keeps one Snapshot
+// per breaker name, whose generation numbers its shared states: every
+// transition, and every new closed interval, increments it.
+//
+// Implementations must be safe for concurrent use by many instances: Add and
+// CompareAndSwap must be atomic with respect to each other, e.g. through Lua
+// scripts, transactions, conditional writes or advisory locks. A snapshot
+// that doesn't exist yet is closed, at generation 0, with zero counts and
+// expiry. Counts.Buckets needs not be kept. See MemoryState for a reference
+// implementation.
+type SharedState interface {
+	// Load returns the shared snapshot of name and whether it exists.
+	Load(ctx context.Context, name string) (Snapshot, bool, error)
+	// CompareAndSwap replaces the shared snapshot of name with s if it is
+	// still at generation old, and reports whether it did. Breakers use it to
+	// publish their transitions.
+	CompareAndSwap(ctx context.Context, name string, old int, s Snapshot) (bool, error)
+	// Add records the outcomes of an instance: it merges delta into the
+	// counts of the shared snapshot of name with Counts.Merge if it is still
+	// at generation, and returns the shared snapshot either way.
+	Add(ctx context.Context, name string, generation int, delta Counts) (Snapshot, error)
+}
+
+// MemoryState is a SharedState keeping snapshots in memory, e.g. to share
+// state between the breakers of one process or in tests.
+type MemoryState struct {
+	mutex     sync.Mutex
+	snapshots map[string]Snapshot
+}
+
+var _ SharedState = &MemoryState{}
+
+// Load implements SharedState.
+func (m *MemoryState) Load(ctx context.Context, name string) (Snapshot, bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	s, ok := m.snapshots[name]
+	return s.copy(), ok, nil
+}
+
+// CompareAndSwap implements SharedState.
+func (m *MemoryState) CompareAndSwap(ctx context.Context, name string, old int, s Snapshot) (bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.snapshots[name].Generation != old {
+		return false, nil
+	}
+	if m.snapshots == nil {
+		m.snapshots = make(map[string]Snapshot)
+	}
+	m.snapshots[name] = s.copy()
+	return true, nil
+}
+
+// Add implements SharedState.
+func (m *MemoryState) Add(ctx context.Context, name string, generation int, delta Counts) (Snapshot, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	s, ok := m.snapshots[name]
+	if !ok {
+		s.Name = name
+		s.State = StateClosed
+	}
+	if s.Generation == generation {
+		s = s.copy()
+		s.Counts.Merge(delta)
+		if m.snapshots == nil {
+			m.snapshots = make(map[string]Snapshot)
+		}
+		m.snapshots[name] = s
+	}
+	return s.copy(), nil
+}
+
+// copy returns a copy of s that doesn't share its Errors map.
+func (s Snapshot) copy() Snapshot {
+	s.Counts.Buckets = nil
+	errs := s.Counts.Errors
+	s.Counts.Errors = nil
+	for class, n := range errs {
+		s.Counts.onErrors(class, n)
+	}
+	return s
+}
+
+// Merge adds the counts of delta, counted after the ones of c, to c. It lets
+// SharedState implementations add up the counts of several instances.
+func (c *Counts) Merge(delta Counts) {
+	c.Requests += delta.Requests
+	c.TotalSuccess += delta.TotalSuccess
+	c.TotalFail += delta.TotalFail
+	c.TotalSlow += delta.TotalSlow
+	for class, n := range delta.Errors {
+		c.onErrors(class, n)
+	}
+
+	switch {
+	case delta.ConsecutiveFail > 0 && delta.TotalSuccess == 0:
+		c.ConsecutiveFail += delta.ConsecutiveFail
+		c.ConsecutiveSuccess = 0
+	case delta.ConsecutiveFail > 0:
+		c.ConsecutiveFail = delta.ConsecutiveFail
+		c.ConsecutiveSuccess = 0
+	case delta.ConsecutiveSuccess > 0 && delta.TotalFail == 0:
+		c.ConsecutiveSuccess += delta.ConsecutiveSuccess
+		c.ConsecutiveFail = 0
+	case delta.ConsecutiveSuccess > 0:
+		c.ConsecutiveSuccess = delta.ConsecutiveSuccess
+		c.ConsecutiveFail = 0
+	}
+}
+
+// maybeSync starts a sync with the shared state if the last one is older
+// than the sync interval. It runs with the breaker locked.
+func (cb *CircuitBreaker) maybeSync(t time.Time) {
+	if cb.syncing || t.Sub(cb.lastSync) < cb.syncInterval {
+		return
+	}
+
+	cb.syncing = true
+	cb.lastSync = t
+	go cb.sync()
+}
+
+// Sync pushes the outcomes recorded since the last sync to the shared state
+// and adopts the shared state right away instead of waiting for the sync
+// interval, e.g. when a SharedState implementation learns of a change. It is
+// a no-op without Settings.SharedState or while a sync is running.
+func (cb *CircuitBreaker) Sync() {
+	cb.mutex.Lock()
+	if cb.shared == nil || cb.syncing {
+		cb.mutex.Unlock()
+		return
+	}
+	cb.syncing = true
+	cb.lastSync = cb.clock.Now()
+	cb.mutex.Unlock()
+
+	cb.sync()
+}
+
+// sync pushes the outcomes recorded since the last sync to the shared state
+// and adopts the shared state if another instance changed it. When the shared
+// state can't be reached the breaker keeps working on its own.
+func (cb *CircuitBreaker) sync() {
+	cb.mutex.Lock()
+	delta, generation := cb.pending, cb.sharedGeneration
+	shared, interval, syncInterval := cb.shared, cb.interval, cb.syncInterval
+	cb.pending = Counts{}
+	cb.mutex.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), syncInterval)
+	defer cancel()
+
+	snap, err := shared.Add(ctx, cb.name, generation, delta)
+
+	now := cb.clock.Now()
+	if err == nil && snap.State == StateClosed && snap.Expiry.Before(now) {
+		next := Snapshot{
+			Name:       cb.name,
+			State:      StateClosed,
+			Expiry:     now.Add(interval),
+			Generation: snap.Generation + 1,
+		}
+
+		var swapped bool
+		swapped, err = shared.CompareAndSwap(ctx, cb.name, snap.Generation, next)
+		if swapped {
+			snap = next
+		}
+	}
+
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	cb.syncing = false
+	if err != nil {
+		cb.logStoreError("sync", err)
+		return
+	}
+
+	if snap.Generation != cb.sharedGeneration {
+		cb.adopt(snap, now)
+		return
+	}
+	if cb.state == StateClosed && cb.override == overrideNone && cb.shouldTrip(snap.Counts) {
+		cb.setState(StateOpen, now)
+	}
+}
+
+// adopt switches the circuit breaker to the shared snapshot s. It runs with the breaker locked.
+func (cb *CircuitBreaker) adopt(s Snapshot, t time.Time) {
+	cb.sharedGeneration = s.Generation
+	if s.State != cb.state {
+		cb.adopting = true
+		cb.setState(s.State, t)
+		cb.adopting = false
+	}
+	if s.State != StateClosed {
+		cb.expiry = s.Expiry
+	}
+	cb.trips = s.Trips
+}
+
+// publish shares a local transition. It runs with the breaker locked.
+// Transitions are published one after the other, each from the generation
+// the previous one left, so that quick successive transitions don't race.
+func (cb *CircuitBreaker) publish() {
+	s := Snapshot{
+		Name:   cb.name,
+		State:  cb.state,
+		Expiry: cb.expiry,
+		Trips:  cb.trips,
+	}
+	shared, syncInterval := cb.shared, cb.syncInterval
+
+	go func() {
+		cb.publishing.Lock()
+		defer cb.publishing.Unlock()
+
+		cb.mutex.Lock()
+		old := cb.sharedGeneration
+		cb.mutex.Unlock()
+		s.Generation = old + 1
+
+		ctx, cancel := context.WithTimeout(context.Background(), syncInterval)
+		defer cancel()
+
+		swapped, err := shared.CompareAndSwap(ctx, cb.name, old, s)
+
+		cb.mutex.Lock()
+		defer cb.mutex.Unlock()
+
+		if err != nil {
+			cb.logStoreError("publish", err)
+			return
+		}
+		if swapped && cb.sharedGeneration == old {
+			cb.sharedGeneration = s.Generation
+		}
+	}()
+}
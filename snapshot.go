@@ -0,0 +1,107 @@
+package breaker
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Snapshot captures the state of a circuit breaker, e.g. to embed it in a
+// health response or to restore it after a restart with Restore.
+type Snapshot struct {
+	Name       string
+	State      State
+	Counts     Counts
+	Expiry     time.Time
+	Generation int
+	// Trips is the number of consecutive trips, which drives the backoff policy.
+	Trips int
+}
+
+type snapshotJSON struct {
+	Name       string     `json:"name"`
+	State      State      `json:"state"`
+	Counts     Counts     `json:"counts"`
+	Expiry     *time.Time `json:"expiry,omitempty"`
+	Generation int        `json:"generation"`
+	Trips      int        `json:"trips"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (s Snapshot) MarshalJSON() ([]byte, error) {
+	v := snapshotJSON{
+		Name:       s.Name,
+		State:      s.State,
+		Counts:     s.Counts,
+		Generation: s.Generation,
+		Trips:      s.Trips,
+	}
+	if !s.Expiry.IsZero() {
+		v.Expiry = &s.Expiry
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (s *Snapshot) UnmarshalJSON(data []byte) error {
+	var v snapshotJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	*s = Snapshot{
+		Name:       v.Name,
+		State:      v.State,
+		Counts:     v.Counts,
+		Generation: v.Generation,
+		Trips:      v.Trips,
+	}
+	if v.Expiry != nil {
+		s.Expiry = *v.Expiry
+	}
+	return nil
+}
+
+// Snapshot returns a snapshot of the circuit breaker.
+func (cb *CircuitBreaker) Snapshot() Snapshot {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	now := cb.clock.Now()
+	state, generation := cb.currentState(now)
+	return Snapshot{
+		Name:       cb.name,
+		State:      state,
+		Counts:     cb.snapshot(now),
+		Expiry:     cb.expiry,
+		Generation: generation,
+		Trips:      cb.trips,
+	}
+}
+
+// Restore puts the circuit breaker in the state, counts and expiry of s, e.g.
+// to keep an open breaker open for the rest of its cool-down after a restart.
+// Requests in flight are not counted afterwards, as on any state change.
+func (cb *CircuitBreaker) Restore(s Snapshot) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	if cb.window != nil {
+		cb.window.clear()
+	}
+
+	cb.state = s.State
+	cb.trips = s.Trips
+	cb.counts = s.Counts
+	cb.counts.Buckets = nil
+	cb.counts.Errors = nil
+	for class, n := range s.Counts.Errors {
+		cb.counts.onErrors(class, n)
+	}
+	cb.expiry = s.Expiry
+	cb.probes = 0
+
+	cb.generation++
+	if s.Generation > cb.generation {
+		cb.generation = s.Generation
+	}
+}
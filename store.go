@@ -0,0 +1,123 @@
+package breaker
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Store persists the snapshots of circuit breakers across restarts, see Settings.Store.
+type Store interface {
+	// Load returns the snapshot saved for the circuit breaker named name,
+	// false if there is none.
+	Load(name string) (Snapshot, bool, error)
+	// Save saves s, replacing the previous snapshot of the same name.
+	Save(s Snapshot) error
+}
+
+// FileStore is a Store keeping one JSON file per circuit breaker in Dir.
+type FileStore struct {
+	Dir string
+}
+
+func (s FileStore) Load(name string) (Snapshot, bool, error) {
+	var snap Snapshot
+
+	data, err := os.ReadFile(s.path(name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return snap, false, nil
+	}
+	if err != nil {
+		return snap, false, err
+	}
+
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return snap, false, err
+	}
+	return snap, true, nil
+}
+
+func (s FileStore) Save(snap Snapshot) error {
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(s.Dir, ".breaker-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(snap.Name))
+}
+
+func (s FileStore) path(name string) string {
+	return filepath.Join(s.Dir, url.PathEscape(name)+".json")
+}
+
+// load restores the snapshot saved in the store, if any.
+func (cb *CircuitBreaker) load() {
+	snap, ok, err := cb.store.Load(cb.name)
+	if err != nil {
+		cb.logStoreError("load", err)
+		return
+	}
+	if ok {
+		cb.Restore(snap)
+	}
+}
+
+// save saves a snapshot of the circuit breaker in the store in the background,
+// so that a slow store doesn't hold up requests. It runs with the breaker locked.
+// When transitions come faster than the store saves, only the latest snapshot
+// is saved.
+func (cb *CircuitBreaker) save(t time.Time) {
+	cb.unsaved = &Snapshot{
+		Name:       cb.name,
+		State:      cb.state,
+		Counts:     cb.snapshot(t),
+		Expiry:     cb.expiry,
+		Generation: cb.generation,
+		Trips:      cb.trips,
+	}
+	if cb.saving {
+		return
+	}
+	cb.saving = true
+	go cb.flush()
+}
+
+// flush saves the pending snapshots one after the other until none is left.
+func (cb *CircuitBreaker) flush() {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	for cb.unsaved != nil {
+		snap, store := *cb.unsaved, cb.store
+		cb.unsaved = nil
+
+		cb.mutex.Unlock()
+		err := store.Save(snap)
+		cb.mutex.Lock()
+
+		if err != nil {
+			cb.logStoreError("save", err)
+		}
+	}
+	cb.saving = false
+}
@@ -0,0 +1,95 @@
+package breaker
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Validate reports the settings that are out of range or that conflict with
+// each other, which NewCircuitBreaker would otherwise silently replace by
+// defaults or ignore. The returned error joins all problems found.
+func (st Settings) Validate() error {
+	var errs []error
+	check := func(bad bool, format string, args ...any) {
+		if bad {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	durations := []struct {
+		name string
+		d    time.Duration
+	}{
+		{"Timeout", st.Timeout},
+		{"OpenTimeout", st.OpenTimeout},
+		{"Interval", st.Interval},
+		{"MaxWait", st.MaxWait},
+		{"RollingWindow", st.RollingWindow},
+		{"SlowCallThreshold", st.SlowCallThreshold},
+		{"CacheTTL", st.CacheTTL},
+		{"SyncInterval", st.SyncInterval},
+	}
+	for _, f := range durations {
+		check(f.d < 0, "%s must not be negative, got %v", f.name, f.d)
+	}
+
+	ints := []struct {
+		name string
+		n    int
+	}{
+		{"MaxRequests", st.MaxRequests},
+		{"MaxConcurrentProbes", st.MaxConcurrentProbes},
+		{"MaxConcurrent", st.MaxConcurrent},
+		{"MaxQueue", st.MaxQueue},
+		{"SuccessThreshold", st.SuccessThreshold},
+		{"WindowSize", st.WindowSize},
+		{"RollingBuckets", st.RollingBuckets},
+		{"MinimumRequests", st.MinimumRequests},
+		{"CacheSize", st.CacheSize},
+		{"EventBuffer", st.EventBuffer},
+	}
+	for _, f := range ints {
+		check(f.n < 0, "%s must not be negative, got %d", f.name, f.n)
+	}
+
+	check(st.FailureRateThreshold < 0 || st.FailureRateThreshold > 1,
+		"FailureRateThreshold must be between 0 and 1, got %v", st.FailureRateThreshold)
+	check(st.SlowCallRateThreshold < 0 || st.SlowCallRateThreshold > 1,
+		"SlowCallRateThreshold must be between 0 and 1, got %v", st.SlowCallRateThreshold)
+
+	check(st.WindowSize > 0 && st.RollingWindow > 0,
+		"WindowSize and RollingWindow are exclusive")
+	check(st.RollingBuckets > 0 && st.RollingWindow == 0,
+		"RollingBuckets is set without RollingWindow")
+	check(st.RollingWindow > 0 && st.RollingBuckets > 0 && st.RollingWindow < time.Duration(st.RollingBuckets),
+		"RollingWindow %v is too short for %d buckets", st.RollingWindow, st.RollingBuckets)
+	check(st.ReadyToTrip != nil && st.FailureRateThreshold > 0,
+		"ReadyToTrip and FailureRateThreshold are exclusive")
+	check(st.MinimumRequests > 0 && st.FailureRateThreshold == 0 && st.SlowCallRateThreshold == 0,
+		"MinimumRequests is set without FailureRateThreshold or SlowCallRateThreshold")
+	check(st.WindowSize > 0 && st.MinimumRequests > st.WindowSize,
+		"MinimumRequests %d exceeds WindowSize %d, the circuit could never trip", st.MinimumRequests, st.WindowSize)
+	check(st.SlowCallRateThreshold > 0 && st.SlowCallThreshold == 0,
+		"SlowCallRateThreshold is set without SlowCallThreshold")
+	maxRequests := st.MaxRequests
+	if maxRequests <= 0 {
+		maxRequests = defaultMaxRequests
+	}
+	check(st.MaxConcurrentProbes == 0 && st.SuccessThreshold > maxRequests,
+		"SuccessThreshold %d exceeds MaxRequests %d, the circuit could never close", st.SuccessThreshold, maxRequests)
+	check(st.SingleProbe && st.MaxConcurrentProbes > 1,
+		"SingleProbe and MaxConcurrentProbes are exclusive")
+	check((st.MaxQueue > 0 || st.MaxWait > 0) && st.MaxConcurrent == 0 && st.AdaptiveConcurrency == nil,
+		"MaxQueue and MaxWait are set without MaxConcurrent or AdaptiveConcurrency")
+
+	return errors.Join(errs...)
+}
+
+// New validates st and returns a new CircuitBreaker, or the problems found by Validate.
+func New(st Settings) (*CircuitBreaker, error) {
+	if err := st.Validate(); err != nil {
+		return nil, err
+	}
+	return NewCircuitBreaker(st), nil
+}
@@ -0,0 +1,163 @@
+package breaker
+
+import "time"
+
+// window aggregates recent outcomes for readyToTrip.
+type window interface {
+	record(success, slow bool, t time.Time)
+	// apply overrides the totals of c with the totals of the window at t.
+	apply(c *Counts, t time.Time)
+	clear()
+}
+
+type outcome struct {
+	success bool
+	slow    bool
+}
+
+// countWindow keeps the outcomes of the last len(outcomes) requests.
+type countWindow struct {
+	outcomes []outcome
+	next     int
+	full     bool
+	success  int
+	fail     int
+	slow     int
+}
+
+func newCountWindow(size int) *countWindow {
+	return &countWindow{outcomes: make([]outcome, size)}
+}
+
+func (w *countWindow) record(success, slow bool, _ time.Time) {
+	if w.full {
+		old := w.outcomes[w.next]
+		if old.success {
+			w.success--
+		} else {
+			w.fail--
+		}
+		if old.slow {
+			w.slow--
+		}
+	}
+
+	w.outcomes[w.next] = outcome{success: success, slow: slow}
+	if success {
+		w.success++
+	} else {
+		w.fail++
+	}
+	if slow {
+		w.slow++
+	}
+
+	w.next++
+	if w.next == len(w.outcomes) {
+		w.next = 0
+		w.full = true
+	}
+}
+
+func (w *countWindow) apply(c *Counts, _ time.Time) {
+	c.Requests = w.success + w.fail
+	c.TotalSuccess = w.success
+	c.TotalFail = w.fail
+	c.TotalSlow = w.slow
+}
+
+func (w *countWindow) clear() {
+	w.next = 0
+	w.full = false
+	w.success = 0
+	w.fail = 0
+	w.slow = 0
+}
+
+// Bucket holds the outcomes recorded during one slice of a rolling time window.
+type Bucket struct {
+	Start   time.Time
+	Success int
+	Fail    int
+	Slow    int
+}
+
+// timeWindow keeps the outcomes of the last len(buckets)*width duration.
+type timeWindow struct {
+	width   time.Duration
+	buckets []Bucket
+}
+
+func newTimeWindow(d time.Duration, n int) *timeWindow {
+	width := d / time.Duration(n)
+	if width <= 0 {
+		width = 1
+	}
+	return &timeWindow{width: width, buckets: make([]Bucket, n)}
+}
+
+func (w *timeWindow) span() time.Duration {
+	return w.width * time.Duration(len(w.buckets))
+}
+
+// index returns the index of the bucket starting at start. It floors the
+// division and keeps the modulo non-negative, so that times before 1970, such
+// as the zero time of a fake clock, map to a valid bucket too.
+func (w *timeWindow) index(start time.Time) int {
+	ns, width, n := start.UnixNano(), int64(w.width), int64(len(w.buckets))
+	i := ns / width
+	if ns%width != 0 && ns < 0 {
+		i--
+	}
+	return int((i%n + n) % n)
+}
+
+func (w *timeWindow) bucket(t time.Time) *Bucket {
+	start := t.Truncate(w.width)
+	b := &w.buckets[w.index(start)]
+	if !b.Start.Equal(start) {
+		*b = Bucket{Start: start}
+	}
+	return b
+}
+
+func (w *timeWindow) record(success, slow bool, t time.Time) {
+	b := w.bucket(t)
+	if success {
+		b.Success++
+	} else {
+		b.Fail++
+	}
+	if slow {
+		b.Slow++
+	}
+}
+
+// apply also fills c.Buckets with the live buckets, oldest first.
+func (w *timeWindow) apply(c *Counts, t time.Time) {
+	c.Requests = 0
+	c.TotalSuccess = 0
+	c.TotalFail = 0
+	c.TotalSlow = 0
+	c.Buckets = make([]Bucket, 0, len(w.buckets))
+
+	newest := w.bucket(t)
+	oldest := newest.Start.Add(-w.span())
+	for i := 1; i <= len(w.buckets); i++ {
+		b := w.buckets[(w.index(newest.Start)+i)%len(w.buckets)]
+		if !b.Start.After(oldest) {
+			continue
+		}
+		c.Requests += b.Success + b.Fail
+		c.TotalSuccess += b.Success
+		c.TotalFail += b.Fail
+		c.TotalSlow += b.Slow
+		c.Buckets = append(c.Buckets, b)
+	}
+}
+
+func (w *timeWindow) clear() {
+	for i := range w.buckets {
+		w.buckets[i] = Bucket{}
+	}
+}